@@ -23,14 +23,67 @@
 package auto
 
 import (
-	"github.com/yroffin/go-boot-sqllite/core/engine"
+	"context"
+	"flag"
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	// engine beans
+	_ "github.com/yroffin/go-boot-sqllite/core/engine"
 	"github.com/yroffin/go-boot-sqllite/core/winter"
 )
 
 // Pack for acess to packr
 var Pack winter.PackManager
 
-func init() {
-	winter.Helper.Init()
-	winter.Helper.GetBean("APIManager").(engine.IAPIManager).CommandLine()
+// Main parse the command line, configure the global logger from the
+// configuration of winter.Helper, then boot it until ctx is done or
+// SIGINT/SIGTERM; importing this package does nothing else, so tests may
+// import it and boot their own managers
+func Main(ctx context.Context, box winter.PackManager, notFound string) error {
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+	config := winter.Helper.GetConfig()
+	if err := config.Load(); err != nil {
+		return err
+	}
+	file, err := Logging(config)
+	if err != nil {
+		return err
+	}
+	if file != nil {
+		defer file.Close()
+		defer log.SetOutput(os.Stdout)
+	}
+	return winter.Helper.Boot(ctx, box, notFound)
+}
+
+// Logging configure the global logger with logging.level (debug) and
+// logging.file (boot.log, none if empty), it returns the opened log file;
+// managers never change the global logger themselves
+func Logging(config winter.IConfig) (*os.File, error) {
+	log.SetFormatter(&log.TextFormatter{})
+	log.SetOutput(os.Stdout)
+	level, err := log.ParseLevel(config.GetString("logging.level", "debug"))
+	if err != nil {
+		return nil, err
+	}
+	log.SetLevel(level)
+	var path = config.GetString("logging.file", "boot.log")
+	if len(path) == 0 {
+		return nil, nil
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"path":  path,
+			"error": err,
+		}).Info("Failed to log to file, using default stdout")
+		return nil, nil
+	}
+	log.SetOutput(io.MultiWriter(os.Stdout, file))
+	return file, nil
 }
@@ -23,6 +23,7 @@
 package engine
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -39,13 +40,28 @@ import (
 var (
 	// Tables Fix tables names
 	Tables = []string{"Node"}
+	// PageQuery query parameters of GetAll and filter handlers, see Query
+	PageQuery = map[string]interface{}{
+		"limit":  "Maximum count of resources",
+		"offset": "Count of resources to skip",
+		"cursor": "X-Next-Cursor of the previous page, replaces offset",
+		"sort":   "Comma separated fields, descending when prefixed by -",
+		"fields": "Comma separated fields to return",
+	}
+	// ErrLinksDisabled returned by link handlers without graph store
+	ErrLinksDisabled = &Error{Kind: NotFound, Message: "links are disabled (graph.enabled)"}
 )
 
 func init() {
-	winter.Helper.Register("graph-crud-business", (&GraphCrudBusiness{}).New())
-	winter.Helper.Register("sql-crud-business", (&SqlCrudBusiness{}).New())
-	winter.Helper.Register("cayley-manager", (&Graph{}).New("./cayley.db"))
-	winter.Helper.Register("sqllite-manager", (&Store{}).New("./sqllite.db"))
+	// graph store can be disabled with graph.enabled=false
+	winter.DefineConditional("graph-crud-business", winter.Singleton, func() winter.IBean { return (&GraphCrudBusiness{}).New() },
+		winter.OnProperty("graph.enabled", "true", true))
+	winter.Define("sql-crud-business", func() winter.IBean { return (&SqlCrudBusiness{}).New() })
+	winter.DefineConditional("cayley-manager", winter.Singleton, func() winter.IBean { return (&Graph{}).New() },
+		winter.OnProperty("graph.enabled", "true", true))
+	// default data store, any other IDataStore bean replaces it
+	winter.DefineConditional("sqllite-manager", winter.Singleton, func() winter.IBean { return (&Store{}).New() },
+		winter.OnMissingType((*IDataStore)(nil)))
 }
 
 // API base class
@@ -53,18 +69,32 @@ type API struct {
 	*winter.Bean
 	// all mthods to declare
 	methods []APIMethod
+	// bean embedding this API
+	self interface{}
 	// Router with injection mecanism
 	Router IRouter `@autowired:"router"`
 	// SqlCrudBusiness with injection mecanism
 	SQLCrudBusiness ICrudBusiness `@autowired:"sql-crud-business"`
 	// GraphBusiness with injection mecanism
-	GraphBusiness ILinkBusiness `@autowired:"graph-crud-business"`
+	GraphBusiness ILinkBusiness `@autowired:"graph-crud-business" @optional:"true"`
+	// Aspects with injection mecanism
+	Aspects winter.IAspects `@autowired:"aspects"`
+	// Jobs of asynchronous tasks with injection mecanism
+	Jobs IJobs `@autowired:"jobs" @optional:"true"`
+	// BulkMaxOperations operations of a bulk at most, more answer 422
+	BulkMaxOperations int `@value:"bulk.max-operations" @default:"1000"`
+	// BulkMaxBytes size of a bulk body at most, larger ones answer 413
+	BulkMaxBytes int64 `@value:"bulk.max-bytes" @default:"10485760"`
+	// tasks by name, see Task
+	tasks map[string]*Task
+	// links declared with @link, see Link
+	links []*Link
 	// Factory
 	Factory          func() models.IPersistent
 	Factories        func() models.IPersistents
 	HandlerTasks     func(name string, body string) (interface{}, int, error)
 	HandlerTasksByID func(id string, name string, body string) (interface{}, int, error)
-	// Adapters
+	// Adapters, see also winter.IAspects
 	GetByIDListener []func(models.IPersistent) models.IPersistent
 	PutByIDListener []func(models.IPersistent) models.IPersistent
 }
@@ -105,6 +135,7 @@ type IAPI interface {
 	// Links
 	GetAllLinks(id string, targetType IAPI) ([]models.IPersistent, error)
 	LoadAllLinks(name string, factory func() models.IPersistent, targetType IAPI) (interface{}, int, error)
+	Cascade(ctx context.Context, id string) error
 }
 
 // GetFactory return on new bean
@@ -126,6 +157,18 @@ func (p *API) GetFactories() models.IPersistents {
 	return nil
 }
 
+// postQuery query parameters of POST on all resources
+func (p *API) postQuery() map[string]interface{} {
+	query := map[string]interface{}{
+		"task":   "Task to execute",
+		"filter": "Get resources matching the filter of the body",
+	}
+	for k, v := range PageQuery {
+		query[k] = v
+	}
+	return query
+}
+
 // Call params
 func Call(params ...interface{}) []reflect.Value {
 	in := make([]reflect.Value, len(params))
@@ -135,14 +178,58 @@ func Call(params ...interface{}) []reflect.Value {
 	return in
 }
 
+// RegisterTask declare a task of this API, see Task
+func (p *API) RegisterTask(name string, fn interface{}, async bool) (*Task, error) {
+	task, err := NewTask(name, fn, async)
+	if err != nil {
+		return nil, err
+	}
+	if p.tasks == nil {
+		p.tasks = make(map[string]*Task)
+	}
+	p.tasks[name] = task
+	return task, nil
+}
+
+// Task get a registered task, on all resources or by id
+func (p *API) Task(name string, byID bool) (*Task, bool) {
+	task, ok := p.tasks[name]
+	if !ok || task.ByID != byID {
+		return nil, false
+	}
+	return task, true
+}
+
 // ScanHandler this API
 func (p *API) ScanHandler(swagger ISwaggerService, ptr interface{}) {
+	p.self = ptr
 	// define all methods
 	types := reflect.TypeOf(ptr).Elem()
 	values := reflect.ValueOf(ptr).Elem()
+	var crud string
+	var tasks = make([]*Task, 0)
 	for i := 0; i < types.NumField(); i++ {
 		field := types.Field(i)
 		value := values.Field(i)
+		// declare a task
+		if len(field.Tag.Get("@task")) > 0 {
+			task, err := p.RegisterTask(field.Tag.Get("@task"), value.Interface(), field.Tag.Get("@async") == "true")
+			if err != nil {
+				log.WithFields(log.Fields{
+					"name":  field.Name,
+					"error": err,
+				}).Warn("Is not task")
+			} else {
+				task.Unit = field.Tag.Get("@unit") == "true"
+				log.WithFields(log.Fields{
+					"name":  field.Name,
+					"task":  task.Name,
+					"async": task.Async,
+					"unit":  task.Unit,
+				}).Info("Task")
+				tasks = append(tasks, task)
+			}
+		}
 		// declare a standard mux handler
 		if len(field.Tag.Get("@handler")) > 0 {
 			log.WithFields(log.Fields{
@@ -154,16 +241,18 @@ func (p *API) ScanHandler(swagger ISwaggerService, ptr interface{}) {
 		if len(field.Tag.Get("@crud")) > 0 {
 			assert, conv := ptr.(IAPI)
 			if conv {
+				crud = field.Tag.Get("@crud")
 				log.WithFields(log.Fields{
 					"name": field.Name,
 				}).Info("Api")
-				p.add(ptr, field.Tag.Get("@crud"), "HandlerStaticGetAll", "GET", "application/json", "Get all", "Get all resources", map[string]interface{}{}, map[string]interface{}{}, []interface{}{}, map[string]interface{}{"200": assert.GetFactories()})
-				p.add(ptr, field.Tag.Get("@crud"), "HandlerStaticPost", "POST", "application/json", "Execute a task or create", "Execute a task on all resources", map[string]interface{}{}, map[string]interface{}{"task": "params"}, []interface{}{assert.GetFactory()}, map[string]interface{}{"200": assert.GetFactory()})
+				p.add(ptr, field.Tag.Get("@crud"), "HandlerStaticGetAll", "GET", "application/json", "Get all", "Get all resources", map[string]interface{}{}, PageQuery, []interface{}{}, map[string]interface{}{"200": assert.GetFactories()})
+				p.add(ptr, field.Tag.Get("@crud"), "HandlerStaticPost", "POST", "application/json", "Execute a task or create", "Execute a task on all resources", map[string]interface{}{}, p.postQuery(), []interface{}{assert.GetFactory()}, map[string]interface{}{"200": assert.GetFactory()})
 				p.add(ptr, field.Tag.Get("@crud")+"/:id", "HandlerStaticGetByID", "GET", "application/json", "Get by id", "Get a resource by its id", map[string]interface{}{"id": "Id"}, map[string]interface{}{}, []interface{}{}, map[string]interface{}{"200": assert.GetFactory()})
-				p.add(ptr, field.Tag.Get("@crud")+"/:id", "HandlerStaticPutByID", "PUT", "application/json", "Update by id", "Update a resource by its id", map[string]interface{}{"id": "Id"}, map[string]interface{}{}, []interface{}{assert.GetFactory()}, map[string]interface{}{"200": assert.GetFactory()})
+				p.add(ptr, field.Tag.Get("@crud")+"/:id", "HandlerStaticPutByID", "PUT", "application/json", "Update by id", "Update a resource by its id", map[string]interface{}{"id": "Id"}, map[string]interface{}{"upsert": "Create the resource with this id when missing"}, []interface{}{assert.GetFactory()}, map[string]interface{}{"200": assert.GetFactory()})
 				p.add(ptr, field.Tag.Get("@crud")+"/:id", "HandlerStaticDeleteByID", "DELETE", "application/json", "Delete by id", "Delete a resource by its id", map[string]interface{}{"id": "Id"}, map[string]interface{}{}, []interface{}{}, map[string]interface{}{"200": assert.GetFactory()})
-				p.add(ptr, field.Tag.Get("@crud")+"/:id", "HandlerStaticPatchByID", "PATCH", "application/json", "Patch by id", "Patch a resource by its id", map[string]interface{}{"id": "Id"}, map[string]interface{}{}, []interface{}{assert.GetFactory()}, map[string]interface{}{"200": assert.GetFactory()})
-				p.add(ptr, field.Tag.Get("@crud")+"/:id", "HandlerStaticPostByID", "POST", "application/json", "Execute a task", "Execute a new task on resource", map[string]interface{}{"id": "Id"}, map[string]interface{}{}, []interface{}{assert.GetFactory()}, map[string]interface{}{"201": assert.GetFactory()})
+				p.add(ptr, field.Tag.Get("@crud")+"/:id", "HandlerStaticPatchByID", "PATCH", "application/json", "Patch by id", "Patch a resource by its id, with a merge patch or a json patch", map[string]interface{}{"id": "Id"}, map[string]interface{}{}, []interface{}{assert.GetFactory()}, map[string]interface{}{"200": assert.GetFactory()})
+				p.add(ptr, field.Tag.Get("@crud")+"/:id/traverse", "HandlerStaticTraverse", "POST", "application/json", "Traverse links", "Follow links of a resource several hops deep", map[string]interface{}{"id": "Id"}, map[string]interface{}{"format": "tree (default) or paths"}, []interface{}{&Traversal{}}, map[string]interface{}{"200": &Branch{}})
+				p.add(ptr, field.Tag.Get("@crud")+"/:id", "HandlerStaticPostByID", "POST", "application/json", "Execute a task", "Execute a new task on resource, or bulk operations with id _bulk", map[string]interface{}{"id": "Id"}, map[string]interface{}{"atomic": "Bulk only, false to keep operations applied when others fail"}, []interface{}{assert.GetFactory()}, map[string]interface{}{"201": assert.GetFactory()})
 			} else {
 				log.WithFields(log.Fields{
 					"name": field.Name,
@@ -176,9 +265,18 @@ func (p *API) ScanHandler(swagger ISwaggerService, ptr interface{}) {
 			assert, conv := value.Interface().(IAPI)
 			if conv {
 				var linkName = field.Tag.Get("@href")
+				onDelete, ok := OnDelete(field.Tag.Get("@ondelete"))
+				if !ok {
+					log.WithFields(log.Fields{
+						"name":     field.Name,
+						"ondelete": field.Tag.Get("@ondelete"),
+					}).Warn("Unknown @ondelete, unlink instead")
+				}
+				p.links = append(p.links, &Link{Href: linkName, Target: assert, OnDelete: onDelete})
 				log.WithFields(log.Fields{
-					"name": field.Name,
-					"link": assert.GetName(),
+					"name":     field.Name,
+					"link":     assert.GetName(),
+					"ondelete": onDelete,
 				}).Info("Api/href")
 				p.addLink(ptr, field.Tag.Get("@link")+"/:id/"+linkName, "HandlerLinkStaticGetAll", "GET", "application/json", "Get all", "Get all resources", map[string]interface{}{"id": "Id"}, map[string]interface{}{}, []interface{}{}, map[string]interface{}{"200": assert.GetFactories()}, assert)
 				p.addLink(ptr, field.Tag.Get("@link")+"/:id/"+linkName+"/:link", "HandlerLinkStaticGetByID", "GET", "application/json", "Get by id", "Get a resource by its id", map[string]interface{}{"id": "Id", "link": "Link"}, map[string]interface{}{}, []interface{}{}, map[string]interface{}{"200": assert.GetFactory()}, assert)
@@ -199,6 +297,18 @@ func (p *API) ScanHandler(swagger ISwaggerService, ptr interface{}) {
 		var typ = strings.SplitAfter(reflect.TypeOf(ptr).String(), ".")
 		swagger.AddPaths(typ[1], p.methods[k].path, p.methods[k].method, p.methods[k].summary, p.methods[k].desc, method.args, method.query, method.in, method.out)
 	}
+	// Add tasks to swagger
+	for _, task := range tasks {
+		if len(crud) == 0 {
+			break
+		}
+		var typ = strings.SplitAfter(reflect.TypeOf(ptr).String(), ".")
+		if task.ByID {
+			swagger.AddTask(typ[1], crud+"/:id", task)
+		} else {
+			swagger.AddTask(typ[1], crud, task)
+		}
+	}
 	// call bean init
 	p.Init()
 }
@@ -281,7 +391,7 @@ func (p *API) Declare(data APIMethod, intf interface{}) error {
 			"mime":    data.typeMime,
 		}).Info("Declare handler GIN/API")
 		// declare it to the router
-		(p.Router).HandleFuncLink(data.path, value, data.method, data.typeMime, data.target)
+		(p.Router).HandleFuncLink(data.path, p.interceptLink(data.handler, value), data.method, data.typeMime, data.target)
 		return nil
 	}
 
@@ -295,7 +405,7 @@ func (p *API) Declare(data APIMethod, intf interface{}) error {
 			"mime":    data.typeMime,
 		}).Info("Declare handler GIN")
 		// declare it to the router
-		(p.Router).HandleFunc(data.path, value, data.method, data.typeMime)
+		(p.Router).HandleFunc(data.path, p.intercept(data.handler, value), data.method, data.typeMime)
 		return nil
 	}
 
@@ -349,15 +459,56 @@ func (p *API) Declare(data APIMethod, intf interface{}) error {
 	return errors.New("Unable to find any type for " + data.handler)
 }
 
+// bean embedding this API, target of interceptors
+func (p *API) bean() interface{} {
+	if p.self != nil {
+		return p.self
+	}
+	return p
+}
+
+// intercept route a handler through aspects, interceptors receive the
+// http context and may answer it without calling Proceed
+func (p *API) intercept(handler string, value func(c IHttpContext)) func(c IHttpContext) {
+	if p.Aspects == nil {
+		return value
+	}
+	return func(c IHttpContext) {
+		p.Aspects.Invoke(p.GetName(), p.bean(), handler, []interface{}{c}, func(args []interface{}) []interface{} {
+			value(args[0].(IHttpContext))
+			return nil
+		})
+	}
+}
+
+// interceptLink route a link handler through aspects
+func (p *API) interceptLink(handler string, value func(c IHttpContext, target IAPI)) func(c IHttpContext, target IAPI) {
+	if p.Aspects == nil {
+		return value
+	}
+	return func(c IHttpContext, target IAPI) {
+		p.Aspects.Invoke(p.GetName(), p.bean(), handler, []interface{}{c, target}, func(args []interface{}) []interface{} {
+			value(args[0].(IHttpContext), args[1].(IAPI))
+			return nil
+		})
+	}
+}
+
 // HandlerStaticGetAll is the GET by ID handler
 func (p *API) HandlerStaticGetAll() func(c IHttpContext) {
 	anonymous := func(c IHttpContext) {
 		c.Header("Content-type", "application/json")
-		data, err := p.GetAll()
+		query, err := ParseQuery(c)
+		if err != nil {
+			p.Problem(c, invalid(p.entity(), "malformed query", err))
+			return
+		}
+		data, page, err := p.Select(query)
 		if err != nil {
-			c.String(400, "{\"message\":\"\"}")
+			p.Problem(c, err)
 			return
 		}
+		p.Paginate(c, page)
 		c.IndentedJSON(200, data)
 	}
 	return anonymous
@@ -369,7 +520,11 @@ func (p *API) HandlerStaticGetByID() func(c IHttpContext) {
 		c.Header("Content-type", "application/json")
 		data, err := p.GetByID(c.Param("id"))
 		if err != nil {
-			c.String(400, "{\"message\":\"\"}")
+			p.Problem(c, err)
+			return
+		}
+		if p.Tag(c, data); IfNoneMatch(c.GetHeader("If-None-Match"), ETag(data)) {
+			c.Status(304)
 			return
 		}
 		c.IndentedJSON(200, data)
@@ -382,10 +537,16 @@ func (p *API) HandlerStaticPost() func(c IHttpContext) {
 	anonymous := func(c IHttpContext) {
 		c.Header("Content-type", "application/json")
 		body, _ := c.GetRawData()
-		if len(c.Query("task")) > 0 {
+		if task, ok := p.Task(c.Query("task"), false); ok {
+			p.HandlerTask(c, task, "", body)
+		} else if len(c.Query("task")) > 0 {
+			if p.HandlerTasks == nil {
+				p.Problem(c, unknownTask(p.entity(), c.Query("task")))
+				return
+			}
 			data, count, err := p.HandlerTasks(c.Query("task"), string(body))
 			if err != nil {
-				c.String(400, "{\"message\":\"\"}")
+				p.Problem(c, err)
 				return
 			}
 			p.XTotalCount(c, count)
@@ -393,20 +554,25 @@ func (p *API) HandlerStaticPost() func(c IHttpContext) {
 		} else {
 			_, ok := c.GetQuery("filter")
 			if ok {
-				var objmap map[string]string
-				err := json.Unmarshal(body, &objmap)
-				data, err := p.HandlerFilter(objmap)
+				query, err := ParseQuery(c)
+				if err != nil {
+					p.Problem(c, invalid(p.entity(), "malformed query", err))
+					return
+				}
+				data, page, err := p.HandlerFilter(string(body), query)
 				if err != nil {
-					c.String(400, "{\"message\":\"\"}")
+					p.Problem(c, err)
 					return
 				}
+				p.Paginate(c, page)
 				c.IndentedJSON(200, data)
 			} else {
 				data, err := p.HandlerPost(string(body))
 				if err != nil {
-					c.String(400, "{\"message\":\"\"}")
+					p.Problem(c, err)
 					return
 				}
+				p.Tag(c, data)
 				c.IndentedJSON(201, data)
 			}
 		}
@@ -414,6 +580,96 @@ func (p *API) HandlerStaticPost() func(c IHttpContext) {
 	return anonymous
 }
 
+// Tag set the ETag header of this bean, if versioned
+func (p *API) Tag(c IHttpContext, data interface{}) {
+	if tag := ETag(data); len(tag) > 0 {
+		c.Header("ETag", tag)
+	}
+}
+
+// Context of this request, it carries the unit of work opened by Work and
+// is done once the client goes away
+func (p *API) Context(c IHttpContext) context.Context {
+	if value, ok := c.Get(ContextKey); ok {
+		if ctx, ok := value.(context.Context); ok {
+			return ctx
+		}
+	}
+	return context.Background()
+}
+
+// Work run fn in the unit of work of this request, opened if needed, with
+// a copy of this API whose entity and link writes belong to this unit;
+// handlers and tasks called meanwhile find it in Context
+func (p *API) Work(c IHttpContext, fn func(api *API) error) error {
+	previous := p.Context(c)
+	return p.SQLCrudBusiness.Work(previous, func(ctx context.Context) error {
+		c.Set(ContextKey, ctx)
+		defer c.Set(ContextKey, previous)
+		return fn(p.within(ctx))
+	})
+}
+
+// HandlerTask run a registered task with the input of this body, async
+// tasks are submitted as jobs and answer 202 with their Location
+func (p *API) HandlerTask(c IHttpContext, task *Task, id string, body []byte) {
+	input, err := task.Input(p.entity(), body)
+	if err != nil {
+		p.Problem(c, err)
+		return
+	}
+	if !task.Async {
+		data, err := p.run(p.Context(c), task, id, input)
+		if err != nil {
+			p.Problem(c, err)
+			return
+		}
+		c.IndentedJSON(200, data)
+		return
+	}
+	if p.Jobs == nil {
+		p.Problem(c, internal(p.entity(), "no job executor for task "+task.Name, nil))
+		return
+	}
+	job, err := p.Jobs.Submit(task.Name, func(ctx context.Context) (interface{}, error) {
+		return p.run(ctx, task, id, input)
+	})
+	if err != nil {
+		p.Problem(c, err)
+		return
+	}
+	c.Header("Location", JobsPath+"/"+job.GetID())
+	c.IndentedJSON(202, job)
+}
+
+// run this task, in a unit of work if declared with @unit
+func (p *API) run(ctx context.Context, task *Task, id string, input interface{}) (interface{}, error) {
+	if !task.Unit {
+		return task.Run(ctx, id, input)
+	}
+	var data interface{}
+	err := p.SQLCrudBusiness.Work(ctx, func(ctx context.Context) error {
+		var err error
+		data, err = task.Run(ctx, id, input)
+		return err
+	})
+	return data, err
+}
+
+// Problem write this error as RFC 7807 problem details, its status
+// comes from its Kind
+func (p *API) Problem(c IHttpContext, err error) {
+	WriteProblem(c, p.entity(), err)
+}
+
+// entity name handled by this API, empty without factory
+func (p *API) entity() string {
+	if p.Factory == nil {
+		return ""
+	}
+	return p.Factory().GetEntityName()
+}
+
 // XTotalCount handle X-Total-Count
 func (p *API) XTotalCount(c IHttpContext, count int) {
 	// handle X-total-count
@@ -422,40 +678,81 @@ func (p *API) XTotalCount(c IHttpContext, count int) {
 	}
 }
 
+// Paginate handle X-Total-Count and X-Next-Cursor of a page
+func (p *API) Paginate(c IHttpContext, page *Page) {
+	if page == nil {
+		return
+	}
+	p.XTotalCount(c, page.Total)
+	if len(page.Next) > 0 {
+		c.Header("X-Next-Cursor", page.Next)
+	}
+}
+
 // HandlerStaticPostByID is the POST handler
 func (p *API) HandlerStaticPostByID() func(c IHttpContext) {
 	anonymous := func(c IHttpContext) {
 		c.Header("Content-type", "application/json")
+		if c.Param("id") == BulkID {
+			limitBody(c, p.BulkMaxBytes)
+		}
 		body, err := c.GetRawData()
 		if err != nil {
-			c.IndentedJSON(400, err)
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				p.Problem(c, &Error{Kind: TooLarge, Entity: p.entity(), Message: "body exceeds " + strconv.FormatInt(tooLarge.Limit, 10) + " bytes"})
+				return
+			}
+			p.Problem(c, invalid(p.entity(), "unreadable body", err))
+			return
+		}
+		if c.Param("id") == BulkID {
+			// bulk operations are atomic by default
+			atomic := true
+			if value, ok := c.GetQuery("atomic"); ok {
+				if atomic, err = strconv.ParseBool(value); err != nil {
+					p.Problem(c, invalid(p.entity(), "malformed atomic", err))
+					return
+				}
+			}
+			reports, status, err := p.HandlerBulk(p.Context(c), string(body), atomic)
+			if err != nil {
+				p.Problem(c, err)
+				return
+			}
+			c.IndentedJSON(status, reports)
 			return
 		}
-		if len(c.Query("task")) > 0 {
-			if c.Param("id") == "*" {
+		if task, ok := p.Task(c.Query("task"), c.Param("id") != "*"); ok {
+			p.HandlerTask(c, task, c.Param("id"), body)
+		} else if len(c.Query("task")) > 0 {
+			if c.Param("id") == "*" && p.HandlerTasks != nil {
 				// id with * is like post on all resources
 				data, count, err := p.HandlerTasks(c.Query("task"), string(body))
 				if err != nil {
-					c.String(400, "{\"message\":\"\"}")
+					p.Problem(c, err)
 					return
 				}
 				p.XTotalCount(c, count)
 				c.IndentedJSON(202, data)
-			} else {
+			} else if c.Param("id") != "*" && p.HandlerTasksByID != nil {
 				data, count, err := p.HandlerTasksByID(c.Param("id"), c.Query("task"), string(body))
 				if err != nil {
-					c.String(400, "{\"message\":\"\"}")
+					p.Problem(c, err)
 					return
 				}
 				p.XTotalCount(c, count)
 				c.IndentedJSON(202, data)
+			} else {
+				p.Problem(c, unknownTask(p.entity(), c.Query("task")))
 			}
 		} else {
 			data, err := p.HandlerPost(string(body))
 			if err != nil {
-				c.String(400, "{\"message\":\"\"}")
+				p.Problem(c, err)
 				return
 			}
+			p.Tag(c, data)
 			c.IndentedJSON(201, data)
 		}
 	}
@@ -467,11 +764,31 @@ func (p *API) HandlerStaticPutByID() func(c IHttpContext) {
 	anonymous := func(c IHttpContext) {
 		c.Header("Content-type", "application/json")
 		body, _ := c.GetRawData()
-		data, err := p.HandlerPutByID(c.Param("id"), string(body))
+		version, err := IfMatch(p.entity(), c.GetHeader("If-Match"))
 		if err != nil {
-			c.String(400, "{\"message\":\"\"}")
+			p.Problem(c, err)
 			return
 		}
+		if _, ok := c.GetQuery("upsert"); ok {
+			data, created, err := p.HandlerUpsertByID(c.Param("id"), string(body), version)
+			if err != nil {
+				p.Problem(c, err)
+				return
+			}
+			p.Tag(c, data)
+			if created {
+				c.IndentedJSON(201, data)
+				return
+			}
+			c.IndentedJSON(200, data)
+			return
+		}
+		data, err := p.HandlerPutByID(c.Param("id"), string(body), version)
+		if err != nil {
+			p.Problem(c, err)
+			return
+		}
+		p.Tag(c, data)
 		c.IndentedJSON(200, data)
 	}
 	return anonymous
@@ -481,9 +798,14 @@ func (p *API) HandlerStaticPutByID() func(c IHttpContext) {
 func (p *API) HandlerStaticDeleteByID() func(c IHttpContext) {
 	anonymous := func(c IHttpContext) {
 		c.Header("Content-type", "application/json")
-		data, err := p.HandlerDeleteByID(c.Param("id"))
+		version, err := IfMatch(p.entity(), c.GetHeader("If-Match"))
 		if err != nil {
-			c.String(400, "{\"message\":\"\"}")
+			p.Problem(c, err)
+			return
+		}
+		data, err := p.HandlerDeleteByID(p.Context(c), c.Param("id"), version)
+		if err != nil {
+			p.Problem(c, err)
 			return
 		}
 		c.IndentedJSON(200, data)
@@ -496,9 +818,30 @@ func (p *API) HandlerStaticPatchByID() func(c IHttpContext) {
 	anonymous := func(c IHttpContext) {
 		c.Header("Content-type", "application/json")
 		body, _ := c.GetRawData()
-		data, err := p.HandlerPatchByID(c.Param("id"), string(body))
+		version, err := IfMatch(p.entity(), c.GetHeader("If-Match"))
+		if err != nil {
+			p.Problem(c, err)
+			return
+		}
+		data, err := p.HandlerPatchByID(c.Param("id"), string(body), c.ContentType(), version)
 		if err != nil {
-			c.String(400, "{\"message\":\"\"}")
+			p.Problem(c, err)
+			return
+		}
+		p.Tag(c, data)
+		c.IndentedJSON(200, data)
+	}
+	return anonymous
+}
+
+// HandlerStaticTraverse is the POST traverse handler
+func (p *API) HandlerStaticTraverse() func(c IHttpContext) {
+	anonymous := func(c IHttpContext) {
+		c.Header("Content-type", "application/json")
+		body, _ := c.GetRawData()
+		data, err := p.HandlerTraverse(c.Param("id"), string(body), c.Query("format"))
+		if err != nil {
+			p.Problem(c, err)
 			return
 		}
 		c.IndentedJSON(200, data)
@@ -512,7 +855,7 @@ func (p *API) HandlerLinkStaticGetAll() func(c IHttpContext, targetType IAPI) {
 		c.Header("Content-type", "application/json")
 		data, err := p.GetAllLinks(c.Param("id"), targetType)
 		if err != nil {
-			c.String(400, "{\"message\":\"\"}")
+			p.Problem(c, err)
 			return
 		}
 		c.IndentedJSON(200, data)
@@ -526,7 +869,7 @@ func (p *API) HandlerLinkStaticGetByID() func(c IHttpContext, targetType IAPI) {
 		c.Header("Content-type", "application/json")
 		data, err := p.GetByID(c.Param("id"))
 		if err != nil {
-			c.String(400, "{\"message\":\"\"}")
+			p.Problem(c, err)
 			return
 		}
 		c.IndentedJSON(200, data)
@@ -539,9 +882,14 @@ func (p *API) HandlerLinkStaticPostByID() func(c IHttpContext, targetType IAPI)
 	anonymous := func(c IHttpContext, targetType IAPI) {
 		c.Header("Content-type", "application/json")
 		body, _ := c.GetRawData()
-		data, err := p.HandlerLinkPostByID(c.Param("id"), c.Param("link"), string(body), targetType)
+		var data models.IPersistent
+		err := p.Work(c, func(api *API) error {
+			var err error
+			data, err = api.HandlerLinkPostByID(c.Param("id"), c.Param("link"), string(body), targetType)
+			return err
+		})
 		if err != nil {
-			c.String(400, "{\"message\":\"\"}")
+			p.Problem(c, err)
 			return
 		}
 		c.IndentedJSON(200, data)
@@ -554,11 +902,22 @@ func (p *API) HandlerLinkStaticPutByID() func(c IHttpContext, targetType IAPI) {
 	anonymous := func(c IHttpContext, targetType IAPI) {
 		c.Header("Content-type", "application/json")
 		body, _ := c.GetRawData()
-		data, err := p.HandlerLinkPutByID(c.Param("id"), c.Param("link"), string(body), targetType, c.Query("instance"))
+		var data models.IPersistent
+		err := p.Work(c, func(api *API) error {
+			if err := api.IfMatchLink(c.Param("id"), targetType, c.Query("instance"), c.GetHeader("If-Match")); err != nil {
+				return err
+			}
+			var err error
+			data, err = api.HandlerLinkPutByID(c.Param("id"), c.Param("link"), string(body), targetType, c.Query("instance"))
+			return err
+		})
 		if err != nil {
-			c.String(400, "{\"message\":\"\"}")
+			p.Problem(c, err)
 			return
 		}
+		if instance, ok := data.GetExtend()["instance"].(string); ok {
+			c.Header("ETag", strconv.Quote(instance))
+		}
 		c.IndentedJSON(200, data)
 	}
 	return anonymous
@@ -569,9 +928,14 @@ func (p *API) HandlerLinkStaticDeleteByID() func(c IHttpContext, targetType IAPI
 	anonymous := func(c IHttpContext, targetType IAPI) {
 		c.Header("Content-type", "application/json")
 		body, _ := c.GetRawData()
-		data, err := p.HandlerLinkDeleteByID(c.Param("id"), c.Param("link"), string(body), targetType, c.Query("instance"))
+		var data interface{}
+		err := p.Work(c, func(api *API) error {
+			var err error
+			data, err = api.HandlerLinkDeleteByID(c.Param("id"), c.Param("link"), string(body), targetType, c.Query("instance"))
+			return err
+		})
 		if err != nil {
-			c.String(400, "{\"message\":\"\"}")
+			p.Problem(c, err)
 			return
 		}
 		c.IndentedJSON(200, data)
@@ -584,11 +948,16 @@ func (p *API) GetAll() ([]models.IPersistent, error) {
 	return p.GenericGetAll(p.Factory(), p.Factories())
 }
 
+// Select get a page of resources
+func (p *API) Select(query *Query) ([]models.IPersistent, *Page, error) {
+	return p.GenericSelect(p.Factory(), p.Factories(), query)
+}
+
 // GetByID get by id
 func (p *API) GetByID(id string) (models.IPersistent, error) {
 	result, err := p.GenericGetByID(id, p.Factory())
 	// Listener middleware
-	if p.GetByIDListener != nil {
+	if p.GetByIDListener != nil && err == nil {
 		for _, adapter := range p.GetByIDListener {
 			result = adapter(result)
 		}
@@ -601,16 +970,22 @@ func (p *API) HandlerPost(body string) (interface{}, error) {
 	return p.GenericPost(body, p.Factory())
 }
 
-// HandlerFilter task handler for filter
-func (p *API) HandlerFilter(body map[string]string) (models.IPersistents, error) {
-	return nil, nil
+// HandlerFilter get a page of beans matching the filter of this body,
+// see Filter
+func (p *API) HandlerFilter(body string, query *Query) ([]models.IPersistent, *Page, error) {
+	filter, err := ParseFilter(body)
+	if err != nil {
+		return nil, nil, invalid(p.entity(), "malformed filter", err)
+	}
+	query.Filter = filter
+	return p.Select(query)
 }
 
-// HandlerPutByID update by id
-func (p *API) HandlerPutByID(id string, body string) (models.IPersistent, error) {
-	result, err := p.GenericPutByID(id, body, p.Factory())
+// HandlerPutByID update by id, at this version if not 0
+func (p *API) HandlerPutByID(id string, body string, version int64) (models.IPersistent, error) {
+	result, err := p.GenericPutByID(id, body, expect(p.Factory(), version))
 	// Listener middleware
-	if p.PutByIDListener != nil {
+	if p.PutByIDListener != nil && err == nil {
 		for _, adapter := range p.PutByIDListener {
 			result = adapter(result)
 		}
@@ -618,67 +993,203 @@ func (p *API) HandlerPutByID(id string, body string) (models.IPersistent, error)
 	return result, err
 }
 
-// HandlerDeleteByID delete by id
-func (p *API) HandlerDeleteByID(id string) (interface{}, error) {
-	return p.GenericDeleteByID(id, p.Factory())
+// HandlerUpsertByID update by id, or create with this id when missing,
+// at this version if not 0
+func (p *API) HandlerUpsertByID(id string, body string, version int64) (models.IPersistent, bool, error) {
+	result, created, err := p.GenericUpsertByID(id, body, expect(p.Factory(), version))
+	// Listener middleware
+	if p.PutByIDListener != nil && err == nil {
+		for _, adapter := range p.PutByIDListener {
+			result = adapter(result)
+		}
+	}
+	return result, created, err
+}
+
+// HandlerDeleteByID delete by id, at this version if not 0
+func (p *API) HandlerDeleteByID(ctx context.Context, id string, version int64) (interface{}, error) {
+	return p.GenericDeleteByID(ctx, id, expect(p.Factory(), version))
+}
+
+// HandlerPatchByID pach by id, with a merge patch or a json patch
+// according to its content type, at this version if not 0
+func (p *API) HandlerPatchByID(id string, body string, contentType string, version int64) (interface{}, error) {
+	return p.GenericPatchByID(id, body, contentType, expect(p.Factory(), version))
+}
+
+// HandlerBulk apply the operations of this body in a single transaction,
+// see ParseBulk, at most BulkMaxOperations of them; the status is 200 when all operations are applied, 207 if
+// some failed, or the status of the failed operation of an atomic bulk
+func (p *API) HandlerBulk(ctx context.Context, body string, atomic bool) ([]Report, int, error) {
+	operations, err := ParseBulk([]byte(body), p.Factory)
+	if err != nil {
+		var typed *Error
+		if !errors.As(err, &typed) {
+			err = invalid(p.entity(), "malformed bulk", err)
+		}
+		return nil, 0, err
+	}
+	if p.BulkMaxOperations > 0 && len(operations) > p.BulkMaxOperations {
+		return nil, 0, invalid(p.entity(), "bulk exceeds "+strconv.Itoa(p.BulkMaxOperations)+" operations", nil)
+	}
+	if _, err := p.GenericBulk(ctx, operations, atomic); err != nil {
+		return nil, 0, err
+	}
+	var status = 200
+	var failed = false
+	for _, operation := range operations {
+		if operation.Err == nil {
+			continue
+		}
+		failed = true
+		status = 207
+		if atomic {
+			status = KindOf(operation.Err).Status()
+		}
+	}
+	return Reports(operations, atomic, failed), status, nil
 }
 
-// HandlerPatchByID pach by id
-func (p *API) HandlerPatchByID(id string, body string) (interface{}, error) {
-	return p.GenericPatchByID(id, body, p.Factory())
+// HandlerTraverse follow links of this resource, answered as a tree or as
+// a flat list of paths
+func (p *API) HandlerTraverse(id string, body string, format string) (interface{}, error) {
+	if format != "" && format != "tree" && format != "paths" {
+		return nil, &Error{Kind: Validation, Entity: p.entity(), Message: "unknown format " + format}
+	}
+	traversal := &Traversal{}
+	if err := p.unmarshal(body, traversal); err != nil {
+		return nil, err
+	}
+	if err := ValidateValue(p.entity(), traversal); err != nil {
+		return nil, err
+	}
+	if _, err := p.GenericGetByID(id, p.Factory()); err != nil {
+		return nil, err
+	}
+	if p.GraphBusiness == nil {
+		return nil, ErrLinksDisabled
+	}
+	traversal.From, traversal.ID = p.entity(), id
+	tree, err := p.GraphBusiness.Traverse(traversal)
+	if err != nil {
+		return nil, err
+	}
+	if format == "paths" {
+		return tree.Paths(), nil
+	}
+	return tree, nil
 }
 
 // HandlerLinkPostByID update by id
 func (p *API) HandlerLinkPostByID(src string, dst string, body string, targetType IAPI) (models.IPersistent, error) {
-	source := p.Factory()
-	p.GenericGetByID(src, source)
-	target := targetType.GetFactory()
-	p.GenericGetByID(dst, target)
-	toCreate := (&models.EdgeBean{}).New(source.GetEntityName(), source.GetID(), targetType.GetName(), target.GetID(), "HREF")
+	source, target, err := p.linked(src, dst, targetType.GetFactory())
+	if err != nil {
+		return nil, err
+	}
+	toCreate := (&models.EdgeBean{}).New(source.GetEntityName(), source.GetID(), targetType.GetName(), target.GetID(), LinkHREF)
+	toCreate.SetName(p.href(targetType))
 	// add edge extended data
 	var ext = make(map[string]interface{})
-	json.Unmarshal([]byte(body), &ext)
+	if err := p.unmarshal(body, &ext); err != nil {
+		return nil, err
+	}
 	toCreate.Extend(ext)
-	_, err := p.GenericLinkPostByID(toCreate)
+	if _, err := p.GenericLinkPostByID(toCreate); err != nil {
+		return nil, err
+	}
 	// edge is reserved keyword
 	delete(ext, "edge")
 	ext["instance"] = toCreate.GetID()
 	target.Extend(ext)
-	return target, err
+	return target, nil
 }
 
 // HandlerLinkPutByID update by id
 func (p *API) HandlerLinkPutByID(src string, dst string, body string, targetType IAPI, instance string) (models.IPersistent, error) {
-	source := p.Factory()
-	p.GenericGetByID(src, source)
-	target := targetType.GetFactory()
-	p.GenericGetByID(dst, target)
-	toUpdate := (&models.EdgeBean{}).New(source.GetEntityName(), source.GetID(), targetType.GetName(), target.GetID(), "HREF")
+	source, target, err := p.linked(src, dst, targetType.GetFactory())
+	if err != nil {
+		return nil, err
+	}
+	toUpdate := (&models.EdgeBean{}).New(source.GetEntityName(), source.GetID(), targetType.GetName(), target.GetID(), LinkHREF)
+	toUpdate.SetName(p.href(targetType))
 	// add edge extended data, edge and instance are reserved keyword
 	var ext = make(map[string]interface{})
-	json.Unmarshal([]byte(body), &ext)
+	if err := p.unmarshal(body, &ext); err != nil {
+		return nil, err
+	}
 	toUpdate.Extend(ext)
 	toUpdate.SetInstance(instance)
-	_, err := p.GenericLinkPutByID(toUpdate)
+	if _, err := p.GenericLinkPutByID(toUpdate); err != nil {
+		return nil, err
+	}
 	// edge is reserved keyword
 	delete(ext, "edge")
-	target.Extend(ext)
 	ext["instance"] = toUpdate.GetID()
-	return target, err
+	target.Extend(ext)
+	return target, nil
+}
+
+// IfMatchLink check an If-Match header against a link instance, its entity
+// tag, which must still link this source
+func (p *API) IfMatchLink(src string, targetType IAPI, instance string, header string) error {
+	values := tags(header)
+	if len(values) == 0 || (len(values) == 1 && values[0] == "*") {
+		return nil
+	}
+	if len(values) > 1 || values[0] != strconv.Quote(instance) {
+		return &Error{Kind: PreconditionFailed, Entity: p.entity(), Message: "If-Match " + header + " does not match link " + instance}
+	}
+	if p.GraphBusiness == nil {
+		return ErrLinksDisabled
+	}
+	edges, err := p.GraphBusiness.GetAllLink(p.entity(), src, make([]models.IEdgeBean, 0), targetType.GetName())
+	if err != nil {
+		return internal(p.entity(), "link failure", err)
+	}
+	for _, edge := range edges {
+		if edge.GetInstance() == instance {
+			return nil
+		}
+	}
+	return &Error{Kind: PreconditionFailed, Entity: p.entity(), Message: "link " + instance + " has changed"}
 }
 
 // HandlerLinkDeleteByID update by id
 func (p *API) HandlerLinkDeleteByID(src string, dst string, body string, targetType IAPI, instance string) (interface{}, error) {
-	source := p.Factory()
-	p.GenericGetByID(src, source)
-	target := p.Factory()
-	p.GenericGetByID(dst, target)
+	if _, _, err := p.linked(src, dst, targetType.GetFactory()); err != nil {
+		return nil, err
+	}
 	toDelete := &models.EdgeBean{}
-	json.Unmarshal([]byte(body), toDelete)
+	if err := p.unmarshal(body, toDelete); err != nil {
+		return nil, err
+	}
 	toDelete.SetInstance(instance)
 	return p.GenericLinkDeleteByID(toDelete)
 }
 
+// linked get both ends of a link
+func (p *API) linked(src string, dst string, target models.IPersistent) (models.IPersistent, models.IPersistent, error) {
+	source, err := p.GenericGetByID(src, p.Factory())
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := p.GenericGetByID(dst, target); err != nil {
+		return nil, nil, err
+	}
+	return source, target, nil
+}
+
+// unmarshal an optional link body
+func (p *API) unmarshal(body string, data interface{}) error {
+	if len(strings.TrimSpace(body)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(body), data); err != nil {
+		return invalid(p.entity(), "malformed body", err)
+	}
+	return nil
+}
+
 // GetAllLinks get all
 func (p *API) GetAllLinks(id string, targetType IAPI) ([]models.IPersistent, error) {
 	return p.GenericLinkGetAll(id, make([]models.IEdgeBean, 0), targetType)
@@ -686,15 +1197,34 @@ func (p *API) GetAllLinks(id string, targetType IAPI) ([]models.IPersistent, err
 
 // GenericGetAll default method
 func (p *API) GenericGetAll(toGet models.IPersistent, toGets models.IPersistents) ([]models.IPersistent, error) {
-	p.SQLCrudBusiness.GetAll(toGet, toGets)
+	if _, err := p.SQLCrudBusiness.GetAll(toGet, toGets); err != nil {
+		return nil, err
+	}
 	return toGets.Get(), nil
 }
 
+// GenericFind default method
+func (p *API) GenericFind(toGet models.IPersistent, toGets models.IPersistents, filter *Filter) ([]models.IPersistent, error) {
+	_, err := p.SQLCrudBusiness.Find(toGet, toGets, filter)
+	if err != nil {
+		return nil, err
+	}
+	return toGets.Get(), nil
+}
+
+// GenericSelect default method
+func (p *API) GenericSelect(toGet models.IPersistent, toGets models.IPersistents, query *Query) ([]models.IPersistent, *Page, error) {
+	_, page, err := p.SQLCrudBusiness.Select(toGet, toGets, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	return toGets.Get(), page, nil
+}
+
 // GenericGetByID default method
 func (p *API) GenericGetByID(id string, toGet models.IPersistent) (models.IPersistent, error) {
 	toGet.SetID(id)
-	p.SQLCrudBusiness.Get(toGet)
-	return toGet, nil
+	return p.SQLCrudBusiness.Get(toGet)
 }
 
 // GenericPost adefault method
@@ -707,60 +1237,136 @@ func (p *API) GenericPost(body string, toCreate models.IPersistent) (interface{}
 			"body":   body,
 			"result": result,
 		}).Error("Unmarshaling body")
-		return body, result
+		return nil, invalid(toCreate.GetEntityName(), "malformed body", result)
 	}
-	bean, _ := p.SQLCrudBusiness.Create(toCreate)
-	return bean, nil
+	return p.SQLCrudBusiness.Create(toCreate)
 }
 
 // GenericPutByID default method
 func (p *API) GenericPutByID(id string, body string, toUpdate models.IPersistent) (models.IPersistent, error) {
 	toUpdate.SetID(id)
 	var bin = []byte(body)
-	json.Unmarshal(bin, &toUpdate)
-	bean, _ := p.SQLCrudBusiness.Update(toUpdate)
-	return bean, nil
+	if err := json.Unmarshal(bin, &toUpdate); err != nil {
+		return nil, invalid(toUpdate.GetEntityName(), "malformed body", err)
+	}
+	// the path id wins over the body one
+	toUpdate.SetID(id)
+	return p.SQLCrudBusiness.Update(toUpdate)
+}
+
+// GenericUpsertByID default method
+func (p *API) GenericUpsertByID(id string, body string, toUpsert models.IPersistent) (models.IPersistent, bool, error) {
+	toUpsert.SetID(id)
+	var bin = []byte(body)
+	if err := json.Unmarshal(bin, &toUpsert); err != nil {
+		return nil, false, invalid(toUpsert.GetEntityName(), "malformed body", err)
+	}
+	// the path id wins over the body one
+	toUpsert.SetID(id)
+	return p.SQLCrudBusiness.Upsert(toUpsert)
 }
 
 // GenericPatchByID default method
-func (p *API) GenericPatchByID(id string, body string, toPatch models.IPersistent) (interface{}, error) {
+func (p *API) GenericPatchByID(id string, body string, contentType string, toPatch models.IPersistent) (interface{}, error) {
+	patch, err := NewPatcher(contentType, []byte(body))
+	if err != nil {
+		return nil, err
+	}
 	toPatch.SetID(id)
-	var bin = []byte(body)
-	json.Unmarshal(bin, &toPatch)
-	bean, _ := p.SQLCrudBusiness.Patch(toPatch)
-	return bean, nil
+	return p.SQLCrudBusiness.Patch(toPatch, patch)
 }
 
-// GenericDeleteByID default method
-func (p *API) GenericDeleteByID(id string, toDelete models.IPersistent) (interface{}, error) {
+// GenericDeleteByID default method, in the unit of work of ctx if any
+func (p *API) GenericDeleteByID(ctx context.Context, id string, toDelete models.IPersistent) (interface{}, error) {
 	toDelete.SetID(id)
-	p.SQLCrudBusiness.Get(toDelete)
-	old, _ := p.SQLCrudBusiness.Delete(toDelete)
-	return old, nil
+	// keep the expected version, get stamps the stored one
+	var expected int64
+	if versioned, ok := toDelete.(models.IVersioned); ok {
+		expected = versioned.GetVersion()
+	}
+	var deleted interface{}
+	err := p.SQLCrudBusiness.Work(ctx, func(ctx context.Context) error {
+		api := p.within(ctx)
+		if _, err := api.SQLCrudBusiness.Get(toDelete); err != nil {
+			return err
+		}
+		// links are released with the row, see Link
+		ctx, _ = deleting(ctx, toDelete)
+		if err := api.release(ctx, toDelete); err != nil {
+			return err
+		}
+		var err error
+		deleted, err = api.SQLCrudBusiness.Delete(expect(toDelete, expected))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// GenericBulk default method, errors of operations are kept in each one;
+// deletes release their links like GenericDeleteByID
+func (p *API) GenericBulk(ctx context.Context, operations []*Operation, atomic bool) ([]*Operation, error) {
+	for _, operation := range operations {
+		operation.release = p.released
+	}
+	err := p.SQLCrudBusiness.Bulk(ctx, operations, atomic)
+	for _, operation := range operations {
+		if err != nil && operation.Err == err {
+			// failure of an atomic bulk
+			return operations, nil
+		}
+	}
+	return operations, err
 }
 
 // GenericLinkPutByID default method
 func (p *API) GenericLinkPostByID(assoc models.IEdgeBean) (interface{}, error) {
-	bean, _ := p.GraphBusiness.CreateLink(assoc)
+	if p.GraphBusiness == nil {
+		return nil, ErrLinksDisabled
+	}
+	bean, err := p.GraphBusiness.CreateLink(assoc)
+	if err != nil {
+		return nil, internal(assoc.GetEntityName(), "link failure", err)
+	}
 	return bean, nil
 }
 
 // GenericLinkPutByID default method
 func (p *API) GenericLinkPutByID(assoc models.IEdgeBean) (interface{}, error) {
-	bean, _ := p.GraphBusiness.UpdateLink(assoc)
+	if p.GraphBusiness == nil {
+		return nil, ErrLinksDisabled
+	}
+	bean, err := p.GraphBusiness.UpdateLink(assoc)
+	if err != nil {
+		return nil, internal(assoc.GetEntityName(), "link failure", err)
+	}
 	return bean, nil
 }
 
 // GenericLinkDeleteByID default method
 func (p *API) GenericLinkDeleteByID(assoc models.IEdgeBean) (interface{}, error) {
-	bean, _ := p.GraphBusiness.DeleteLink(assoc)
+	if p.GraphBusiness == nil {
+		return nil, ErrLinksDisabled
+	}
+	bean, err := p.GraphBusiness.DeleteLink(assoc)
+	if err != nil {
+		return nil, internal(assoc.GetEntityName(), "link failure", err)
+	}
 	return bean, nil
 }
 
 // GenericLinkGetAll default method
 func (p *API) GenericLinkGetAll(id string, links []models.IEdgeBean, targetType IAPI) ([]models.IPersistent, error) {
+	if p.GraphBusiness == nil {
+		return nil, ErrLinksDisabled
+	}
 	// Retrieve all links
-	edges, _ := p.GraphBusiness.GetAllLink(p.GetFactory().GetEntityName(), id, links, targetType.GetName())
+	edges, err := p.GraphBusiness.GetAllLink(p.GetFactory().GetEntityName(), id, links, targetType.GetName())
+	if err != nil {
+		return nil, internal(p.entity(), "link failure", err)
+	}
 	// Build output
 	output := make([]models.IPersistent, 0)
 	for _, edge := range edges {
@@ -769,7 +1375,14 @@ func (p *API) GenericLinkGetAll(id string, links []models.IEdgeBean, targetType
 		// Filter by type
 		if edge.GetTarget() == t.GetEntityName() {
 			t.SetID(edge.GetTargetID())
-			p.SQLCrudBusiness.Get(t)
+			_, err := p.SQLCrudBusiness.Get(t)
+			if KindOf(err) == NotFound {
+				// dangling link
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
 			ex := make(map[string]interface{})
 			ex["instance"] = edge.GetInstance()
 			ex["edge"] = edge
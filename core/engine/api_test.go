@@ -0,0 +1,103 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yroffin/go-boot-sqllite/core/models"
+)
+
+// call this handler of a resource id, with an optional body
+func call(handler func(c IHttpContext), method string, id string, query string, body string, contentType string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(method, "/api/node/"+id+query, bytes.NewBufferString(body))
+	if len(contentType) > 0 {
+		c.Request.Header.Set("Content-Type", contentType)
+	}
+	c.Params = gin.Params{{Key: "id", Value: id}}
+	handler(c)
+	return recorder
+}
+
+func TestMissingID(t *testing.T) {
+	store := memoryStore(t)
+	defer store.PreDestroy("store")
+	api := nodeAPI(store)
+	tests := []struct {
+		name    string
+		handler func(c IHttpContext)
+		method  string
+		body    string
+		// content type of the body
+		contentType string
+	}{
+		{"get", api.HandlerStaticGetByID(), "GET", "", ""},
+		{"put", api.HandlerStaticPutByID(), "PUT", `{"name":"temp"}`, "application/json"},
+		{"patch", api.HandlerStaticPatchByID(), "PATCH", `{"name":"temp"}`, MergePatch},
+		{"delete", api.HandlerStaticDeleteByID(), "DELETE", "", ""},
+	}
+	for _, test := range tests {
+		recorder := call(test.handler, test.method, "missing", "", test.body, test.contentType)
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("%s: got %d %s", test.name, recorder.Code, recorder.Body)
+		}
+	}
+	nodes := (&models.NodeBeans{}).New()
+	if err := store.GetAll(&models.NodeBean{}, nodes); err != nil || len(nodes.Get()) != 0 {
+		t.Errorf("stored %v %v", names(nodes), err)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	store := memoryStore(t)
+	defer store.PreDestroy("store")
+	api := nodeAPI(store)
+	for _, test := range []struct {
+		name   string
+		status int
+	}{
+		{"created", http.StatusCreated},
+		{"updated", http.StatusOK},
+	} {
+		recorder := call(api.HandlerStaticPutByID(), "PUT", "sensor-1", "?upsert", `{"id":"other","name":"`+test.name+`"}`, "application/json")
+		bean := (&models.NodeBean{}).New().(*models.NodeBean)
+		if err := json.Unmarshal(recorder.Body.Bytes(), bean); err != nil || recorder.Code != test.status || bean.ID != "sensor-1" {
+			t.Errorf("%s: got %d %s", test.name, recorder.Code, recorder.Body)
+		}
+		if len(recorder.Header().Get("ETag")) == 0 {
+			t.Errorf("%s: no ETag", test.name)
+		}
+	}
+	// the path id wins over the body one
+	upserted := (&models.NodeBean{}).New().(*models.NodeBean)
+	if err := store.Get("sensor-1", upserted); err != nil || upserted.Name != "updated" {
+		t.Errorf("got %+v %v", upserted, err)
+	}
+}
@@ -0,0 +1,378 @@
+// Package business for business interface
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"context"
+
+	"github.com/yroffin/go-boot-sqllite/core/models"
+	"github.com/yroffin/go-boot-sqllite/core/winter"
+)
+
+func init() {
+	winter.RegisterProxy((*ICrudBusiness)(nil), func(name string, bean interface{}, aspects winter.IAspects) interface{} {
+		return &crudProxy{ICrudBusiness: bean.(ICrudBusiness), name: name, aspects: aspects}
+	})
+	winter.RegisterProxy((*ILinkBusiness)(nil), func(name string, bean interface{}, aspects winter.IAspects) interface{} {
+		return &linkProxy{ILinkBusiness: bean.(ILinkBusiness), name: name, aspects: aspects}
+	})
+}
+
+// outcome get a result by index, missing results of a short-circuited
+// call are nil
+func outcome(results []interface{}, index int) interface{} {
+	if index < len(results) {
+		return results[index]
+	}
+	return nil
+}
+
+// failure get the error result
+func failure(results []interface{}, index int) error {
+	err, _ := outcome(results, index).(error)
+	return err
+}
+
+// persistent get a persistent result
+func persistent(results []interface{}, index int) models.IPersistent {
+	result, _ := outcome(results, index).(models.IPersistent)
+	return result
+}
+
+// edge get an edge result
+func edge(results []interface{}, index int) models.IEdgeBean {
+	result, _ := outcome(results, index).(models.IEdgeBean)
+	return result
+}
+
+// boundCrud the crud of a unit of work, routed through the aspects of
+// current if it is a proxy and bound is not yet
+func boundCrud(current ICrudBusiness, bound ICrudBusiness) ICrudBusiness {
+	if _, ok := bound.(*crudProxy); ok || bound == nil {
+		return bound
+	}
+	if proxy, ok := current.(*crudProxy); ok {
+		return &crudProxy{ICrudBusiness: bound, name: proxy.name, aspects: proxy.aspects}
+	}
+	return bound
+}
+
+// boundLinks the links of a unit of work, routed through the aspects of
+// current if it is a proxy and bound is not yet
+func boundLinks(current ILinkBusiness, bound ILinkBusiness) ILinkBusiness {
+	if _, ok := bound.(*linkProxy); ok || bound == nil {
+		return bound
+	}
+	if proxy, ok := current.(*linkProxy); ok {
+		return &linkProxy{ILinkBusiness: bound, name: proxy.name, aspects: proxy.aspects}
+	}
+	return bound
+}
+
+// sqlCrud the sql crud behind this crud, if any
+func sqlCrud(crud ICrudBusiness) (*SqlCrudBusiness, bool) {
+	if proxy, ok := crud.(*crudProxy); ok {
+		crud = proxy.ICrudBusiness
+	}
+	bound, ok := crud.(*SqlCrudBusiness)
+	return bound, ok
+}
+
+// crudProxy route ICrudBusiness calls through aspects
+type crudProxy struct {
+	ICrudBusiness
+	name    string
+	aspects winter.IAspects
+}
+
+// invoke a crud method through aspects
+func (p *crudProxy) invoke(method string, args []interface{}, proceed func([]interface{}) []interface{}) []interface{} {
+	return p.aspects.Invoke(p.name, p.ICrudBusiness, method, args, proceed)
+}
+
+// GetAll intercepted
+func (p *crudProxy) GetAll(toGet models.IPersistent, toGets models.IPersistents) (models.IPersistents, error) {
+	results := p.invoke("GetAll", []interface{}{toGet, toGets}, func(args []interface{}) []interface{} {
+		result, err := p.ICrudBusiness.GetAll(args[0].(models.IPersistent), args[1].(models.IPersistents))
+		return []interface{}{result, err}
+	})
+	result, _ := outcome(results, 0).(models.IPersistents)
+	return result, failure(results, 1)
+}
+
+// Find intercepted
+func (p *crudProxy) Find(toGet models.IPersistent, toGets models.IPersistents, filter *Filter) (models.IPersistents, error) {
+	results := p.invoke("Find", []interface{}{toGet, toGets, filter}, func(args []interface{}) []interface{} {
+		result, err := p.ICrudBusiness.Find(args[0].(models.IPersistent), args[1].(models.IPersistents), args[2].(*Filter))
+		return []interface{}{result, err}
+	})
+	result, _ := outcome(results, 0).(models.IPersistents)
+	return result, failure(results, 1)
+}
+
+// Select intercepted
+func (p *crudProxy) Select(toGet models.IPersistent, toGets models.IPersistents, query *Query) (models.IPersistents, *Page, error) {
+	results := p.invoke("Select", []interface{}{toGet, toGets, query}, func(args []interface{}) []interface{} {
+		result, page, err := p.ICrudBusiness.Select(args[0].(models.IPersistent), args[1].(models.IPersistents), args[2].(*Query))
+		return []interface{}{result, page, err}
+	})
+	result, _ := outcome(results, 0).(models.IPersistents)
+	page, _ := outcome(results, 1).(*Page)
+	return result, page, failure(results, 2)
+}
+
+// Get intercepted
+func (p *crudProxy) Get(toGet models.IPersistent) (models.IPersistent, error) {
+	results := p.invoke("Get", []interface{}{toGet}, func(args []interface{}) []interface{} {
+		result, err := p.ICrudBusiness.Get(args[0].(models.IPersistent))
+		return []interface{}{result, err}
+	})
+	return persistent(results, 0), failure(results, 1)
+}
+
+// Create intercepted
+func (p *crudProxy) Create(toCreate models.IPersistent) (models.IPersistent, error) {
+	results := p.invoke("Create", []interface{}{toCreate}, func(args []interface{}) []interface{} {
+		result, err := p.ICrudBusiness.Create(args[0].(models.IPersistent))
+		return []interface{}{result, err}
+	})
+	return persistent(results, 0), failure(results, 1)
+}
+
+// Update intercepted
+func (p *crudProxy) Update(toUpdate models.IPersistent) (models.IPersistent, error) {
+	results := p.invoke("Update", []interface{}{toUpdate}, func(args []interface{}) []interface{} {
+		result, err := p.ICrudBusiness.Update(args[0].(models.IPersistent))
+		return []interface{}{result, err}
+	})
+	return persistent(results, 0), failure(results, 1)
+}
+
+// Upsert intercepted
+func (p *crudProxy) Upsert(toUpsert models.IPersistent) (models.IPersistent, bool, error) {
+	results := p.invoke("Upsert", []interface{}{toUpsert}, func(args []interface{}) []interface{} {
+		result, created, err := p.ICrudBusiness.Upsert(args[0].(models.IPersistent))
+		return []interface{}{result, created, err}
+	})
+	created, _ := outcome(results, 1).(bool)
+	return persistent(results, 0), created, failure(results, 2)
+}
+
+// Delete intercepted
+func (p *crudProxy) Delete(toDelete models.IPersistent) (models.IPersistent, error) {
+	results := p.invoke("Delete", []interface{}{toDelete}, func(args []interface{}) []interface{} {
+		result, err := p.ICrudBusiness.Delete(args[0].(models.IPersistent))
+		return []interface{}{result, err}
+	})
+	return persistent(results, 0), failure(results, 1)
+}
+
+// Patch intercepted
+func (p *crudProxy) Patch(toPatch models.IPersistent, patch Patcher) (models.IPersistent, error) {
+	results := p.invoke("Patch", []interface{}{toPatch, patch}, func(args []interface{}) []interface{} {
+		result, err := p.ICrudBusiness.Patch(args[0].(models.IPersistent), args[1].(Patcher))
+		return []interface{}{result, err}
+	})
+	return persistent(results, 0), failure(results, 1)
+}
+
+// Bulk intercepted
+func (p *crudProxy) Bulk(ctx context.Context, operations []*Operation, atomic bool) error {
+	results := p.invoke("Bulk", []interface{}{ctx, operations, atomic}, func(args []interface{}) []interface{} {
+		return []interface{}{p.ICrudBusiness.Bulk(args[0].(context.Context), args[1].([]*Operation), args[2].(bool))}
+	})
+	return failure(results, 0)
+}
+
+// Work intercepted
+func (p *crudProxy) Work(ctx context.Context, fn func(context.Context) error) error {
+	results := p.invoke("Work", []interface{}{ctx, fn}, func(args []interface{}) []interface{} {
+		fn := args[1].(func(context.Context) error)
+		return []interface{}{p.ICrudBusiness.Work(args[0].(context.Context), func(ctx context.Context) error {
+			// the crud of the unit goes through the same aspects
+			if unit, ok := UnitOf(ctx); ok {
+				if crud := boundCrud(p, unit.Crud); crud != unit.Crud {
+					ctx = WithUnit(ctx, &Unit{Crud: crud, Links: unit.Links})
+				}
+			}
+			return fn(ctx)
+		})}
+	})
+	return failure(results, 0)
+}
+
+// Clear intercepted
+func (p *crudProxy) Clear(excp []string) error {
+	results := p.invoke("Clear", []interface{}{excp}, func(args []interface{}) []interface{} {
+		return []interface{}{p.ICrudBusiness.Clear(args[0].([]string))}
+	})
+	return failure(results, 0)
+}
+
+// Statistics intercepted
+func (p *crudProxy) Statistics() ([]IStats, error) {
+	results := p.invoke("Statistics", []interface{}{}, func(args []interface{}) []interface{} {
+		result, err := p.ICrudBusiness.Statistics()
+		return []interface{}{result, err}
+	})
+	result, _ := outcome(results, 0).([]IStats)
+	return result, failure(results, 1)
+}
+
+// linkProxy route ILinkBusiness calls through aspects
+type linkProxy struct {
+	ILinkBusiness
+	name    string
+	aspects winter.IAspects
+}
+
+// invoke a link method through aspects
+func (p *linkProxy) invoke(method string, args []interface{}, proceed func([]interface{}) []interface{}) []interface{} {
+	return p.aspects.Invoke(p.name, p.ILinkBusiness, method, args, proceed)
+}
+
+// CreateLink intercepted
+func (p *linkProxy) CreateLink(toCreate models.IEdgeBean) (models.IEdgeBean, error) {
+	results := p.invoke("CreateLink", []interface{}{toCreate}, func(args []interface{}) []interface{} {
+		result, err := p.ILinkBusiness.CreateLink(args[0].(models.IEdgeBean))
+		return []interface{}{result, err}
+	})
+	return edge(results, 0), failure(results, 1)
+}
+
+// UpdateLink intercepted
+func (p *linkProxy) UpdateLink(toUpdate models.IEdgeBean) (models.IEdgeBean, error) {
+	results := p.invoke("UpdateLink", []interface{}{toUpdate}, func(args []interface{}) []interface{} {
+		result, err := p.ILinkBusiness.UpdateLink(args[0].(models.IEdgeBean))
+		return []interface{}{result, err}
+	})
+	return edge(results, 0), failure(results, 1)
+}
+
+// DeleteLink intercepted
+func (p *linkProxy) DeleteLink(toDelete models.IEdgeBean) (models.IEdgeBean, error) {
+	results := p.invoke("DeleteLink", []interface{}{toDelete}, func(args []interface{}) []interface{} {
+		result, err := p.ILinkBusiness.DeleteLink(args[0].(models.IEdgeBean))
+		return []interface{}{result, err}
+	})
+	return edge(results, 0), failure(results, 1)
+}
+
+// PatchLink intercepted
+func (p *linkProxy) PatchLink(toPatch models.IEdgeBean) (models.IEdgeBean, error) {
+	results := p.invoke("PatchLink", []interface{}{toPatch}, func(args []interface{}) []interface{} {
+		result, err := p.ILinkBusiness.PatchLink(args[0].(models.IEdgeBean))
+		return []interface{}{result, err}
+	})
+	return edge(results, 0), failure(results, 1)
+}
+
+// GetAllLink intercepted
+func (p *linkProxy) GetAllLink(model string, id string, toGets []models.IEdgeBean, targetType string) ([]models.IEdgeBean, error) {
+	results := p.invoke("GetAllLink", []interface{}{model, id, toGets, targetType}, func(args []interface{}) []interface{} {
+		result, err := p.ILinkBusiness.GetAllLink(args[0].(string), args[1].(string), args[2].([]models.IEdgeBean), args[3].(string))
+		return []interface{}{result, err}
+	})
+	result, _ := outcome(results, 0).([]models.IEdgeBean)
+	return result, failure(results, 1)
+}
+
+// GetAllLinkTo intercepted
+func (p *linkProxy) GetAllLinkTo(model string, id string, toGets []models.IEdgeBean) ([]models.IEdgeBean, error) {
+	results := p.invoke("GetAllLinkTo", []interface{}{model, id, toGets}, func(args []interface{}) []interface{} {
+		result, err := p.ILinkBusiness.GetAllLinkTo(args[0].(string), args[1].(string), args[2].([]models.IEdgeBean))
+		return []interface{}{result, err}
+	})
+	result, _ := outcome(results, 0).([]models.IEdgeBean)
+	return result, failure(results, 1)
+}
+
+// Traverse intercepted
+func (p *linkProxy) Traverse(traversal *Traversal) (*Branch, error) {
+	results := p.invoke("Traverse", []interface{}{traversal}, func(args []interface{}) []interface{} {
+		result, err := p.ILinkBusiness.Traverse(args[0].(*Traversal))
+		return []interface{}{result, err}
+	})
+	result, _ := outcome(results, 0).(*Branch)
+	return result, failure(results, 1)
+}
+
+// Query intercepted
+func (p *linkProxy) Query(ctx context.Context, query *GraphQuery) (*GraphResult, error) {
+	results := p.invoke("Query", []interface{}{ctx, query}, func(args []interface{}) []interface{} {
+		result, err := p.ILinkBusiness.Query(args[0].(context.Context), args[1].(*GraphQuery))
+		return []interface{}{result, err}
+	})
+	result, _ := outcome(results, 0).(*GraphResult)
+	return result, failure(results, 1)
+}
+
+// Clear intercepted
+func (p *linkProxy) Clear() error {
+	results := p.invoke("Clear", []interface{}{}, func(args []interface{}) []interface{} {
+		return []interface{}{p.ILinkBusiness.Clear()}
+	})
+	return failure(results, 0)
+}
+
+// All intercepted
+func (p *linkProxy) All() ([]IQuad, error) {
+	results := p.invoke("All", []interface{}{}, func(args []interface{}) []interface{} {
+		result, err := p.ILinkBusiness.All()
+		return []interface{}{result, err}
+	})
+	result, _ := outcome(results, 0).([]IQuad)
+	return result, failure(results, 1)
+}
+
+// Statistics intercepted
+func (p *linkProxy) Statistics() ([]IStats, error) {
+	results := p.invoke("Statistics", []interface{}{}, func(args []interface{}) []interface{} {
+		result, err := p.ILinkBusiness.Statistics()
+		return []interface{}{result, err}
+	})
+	result, _ := outcome(results, 0).([]IStats)
+	return result, failure(results, 1)
+}
+
+// Export intercepted
+func (p *linkProxy) Export() (map[string][]map[string]interface{}, error) {
+	results := p.invoke("Export", []interface{}{}, func(args []interface{}) []interface{} {
+		result, err := p.ILinkBusiness.Export()
+		return []interface{}{result, err}
+	})
+	result, _ := outcome(results, 0).(map[string][]map[string]interface{})
+	return result, failure(results, 1)
+}
+
+// Transaction intercepted
+func (p *linkProxy) Transaction(fn func(ILinkBusiness) error) error {
+	results := p.invoke("Transaction", []interface{}{fn}, func(args []interface{}) []interface{} {
+		fn := args[0].(func(ILinkBusiness) error)
+		return []interface{}{p.ILinkBusiness.Transaction(func(links ILinkBusiness) error {
+			// bound links go through the same aspects
+			return fn(boundLinks(p, links))
+		})}
+	})
+	return failure(results, 0)
+}
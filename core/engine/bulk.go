@@ -0,0 +1,207 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/yroffin/go-boot-sqllite/core/models"
+)
+
+const (
+	// BulkID id of the bulk resource, POST /api/<resource>/_bulk
+	BulkID = "_bulk"
+)
+
+// Operation a bulk operation on one entity
+type Operation struct {
+	// Op create, update, upsert or delete
+	Op string
+	// Entity with its id and its expected version, if any
+	Entity models.IPersistent
+	// Created true if an upsert created its entity
+	Created bool
+	// Err of this operation, nil once applied
+	Err error
+	// release the links of a deleted entity, see API.GenericBulk
+	release func(ctx context.Context, entity models.IPersistent) error
+	// released true once links were released
+	released bool
+}
+
+// apply this operation with this store, in the unit of work of ctx
+func (o *Operation) apply(ctx context.Context, store IDataStore) error {
+	var entity = o.Entity
+	switch o.Op {
+	case "create":
+		if err := Validate(entity); err != nil {
+			return err
+		}
+		return store.Create(entity)
+	case "update":
+		if err := Validate(entity); err != nil {
+			return err
+		}
+		return store.Update(entity.GetID(), entity)
+	case "upsert":
+		if err := Validate(entity); err != nil {
+			return err
+		}
+		var err error
+		o.Created, err = store.Upsert(entity.GetID(), entity)
+		return err
+	case "delete":
+		// keep the expected version, get stamps the stored one
+		var expected int64
+		if versioned, ok := entity.(models.IVersioned); ok {
+			expected = versioned.GetVersion()
+		}
+		if err := store.Get(entity.GetID(), entity); err != nil {
+			return err
+		}
+		if versioned, ok := entity.(models.IVersioned); ok && expected != 0 && versioned.GetVersion() != expected {
+			// stale deletes fail before their links are released
+			return stale(entity.GetEntityName(), entity.GetID(), expected)
+		}
+		if o.release != nil {
+			o.released = true
+			if err := o.release(ctx, entity); err != nil {
+				return err
+			}
+		}
+		return store.Delete(entity.GetID(), expect(entity, expected))
+	}
+	return invalid(entity.GetEntityName(), "unknown operation "+strconv.Quote(o.Op), nil)
+}
+
+// event published once this operation is committed
+func (o *Operation) event() interface{} {
+	switch {
+	case o.Op == "create" || o.Created:
+		return EntityCreated{Entity: o.Entity}
+	case o.Op == "delete":
+		return EntityDeleted{Entity: o.Entity}
+	}
+	return EntityUpdated{Entity: o.Entity}
+}
+
+// status of this operation once applied
+func (o *Operation) status() int {
+	if o.Op == "create" || o.Created {
+		return 201
+	}
+	return 200
+}
+
+// bulkLine a line of a bulk body
+type bulkLine struct {
+	Op      string          `json:"op"`
+	ID      string          `json:"id"`
+	Version int64           `json:"version"`
+	Body    json.RawMessage `json:"body"`
+}
+
+// Report the outcome of a bulk operation
+type Report struct {
+	Index  int                `json:"index"`
+	Op     string             `json:"op"`
+	ID     string             `json:"id,omitempty"`
+	Status int                `json:"status"`
+	ETag   string             `json:"etag,omitempty"`
+	Entity models.IPersistent `json:"entity,omitempty"`
+	Error  *Problem           `json:"error,omitempty"`
+}
+
+// ParseBulk decode a bulk body, an array or newline delimited json of
+//
+//	{"op":"create","body":{...}}
+//	{"op":"update","id":"...","version":3,"body":{...}}
+//	{"op":"delete","id":"..."}
+//
+// bodies are decoded in beans of this factory
+func ParseBulk(body []byte, factory func() models.IPersistent) ([]*Operation, error) {
+	lines := make([]bulkLine, 0)
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &lines); err != nil {
+			return nil, err
+		}
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		for {
+			var line bulkLine
+			if err := decoder.Decode(&line); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			lines = append(lines, line)
+		}
+	}
+	operations := make([]*Operation, 0, len(lines))
+	for index, line := range lines {
+		entity := factory()
+		if len(line.Body) > 0 {
+			if err := json.Unmarshal(line.Body, &entity); err != nil {
+				return nil, invalid(entity.GetEntityName(), "malformed body of operation "+strconv.Itoa(index), err)
+			}
+		}
+		if line.Op != "create" {
+			if len(line.ID) == 0 {
+				return nil, invalid(entity.GetEntityName(), "operation "+strconv.Itoa(index)+" has no id", nil)
+			}
+			// the line id wins over the body one
+			entity.SetID(line.ID)
+		}
+		operations = append(operations, &Operation{Op: line.Op, Entity: expect(entity, line.Version)})
+	}
+	return operations, nil
+}
+
+// Reports of these operations, operations of a failed atomic bulk are all
+// rolled back
+func Reports(operations []*Operation, atomic bool, failed bool) []Report {
+	reports := make([]Report, 0, len(operations))
+	for index, operation := range operations {
+		report := Report{Index: index, Op: operation.Op, ID: operation.Entity.GetID()}
+		switch {
+		case operation.Err != nil:
+			problem := NewProblem(operation.Entity.GetEntityName(), operation.Err)
+			report.Status, report.Error = problem.Status, &problem
+		case atomic && failed:
+			if operation.Op == "create" {
+				// its generated id was rolled back
+				report.ID = ""
+			}
+			problem := Problem{Type: "about:blank", Title: "Failed Dependency", Status: 424, Detail: "not applied, the bulk is atomic", Entity: operation.Entity.GetEntityName()}
+			report.Status, report.Error = problem.Status, &problem
+		default:
+			report.Status, report.ETag, report.Entity = operation.status(), ETag(operation.Entity), operation.Entity
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
@@ -0,0 +1,203 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yroffin/go-boot-sqllite/core/models"
+	"github.com/yroffin/go-boot-sqllite/core/winter"
+)
+
+func TestBulkDeleteRelease(t *testing.T) {
+	beans := []*models.NodeBean{node("a", "", nil), node("b", "", nil), node("c", "", nil)}
+	store := memoryStore(t, beans...)
+	defer store.PreDestroy("store")
+	crud := &SqlCrudBusiness{Store: store, Events: (&winter.EventBus{}).New()}
+	released := make([]string, 0)
+	release := func(ctx context.Context, entity models.IPersistent) error {
+		if _, ok := UnitOf(ctx); !ok {
+			t.Error("release outside the unit of work")
+		}
+		released = append(released, entity.(*models.NodeBean).Name)
+		return nil
+	}
+	remove := func(id string, version int64) *Operation {
+		entity := expect((&models.NodeBean{}).New().(*models.NodeBean), version)
+		entity.SetID(id)
+		return &Operation{Op: "delete", Entity: entity, release: release}
+	}
+	operations := []*Operation{
+		remove(beans[0].ID, 0),
+		remove(beans[1].ID, 5),
+		remove("missing", 0),
+		remove(beans[2].ID, 1),
+	}
+	if err := crud.Bulk(context.Background(), operations, false); err != nil {
+		t.Fatal(err)
+	}
+	for index, kind := range []Kind{-1, PreconditionFailed, NotFound, -1} {
+		if err := operations[index].Err; (kind < 0) != (err == nil) || (err != nil && KindOf(err) != kind) {
+			t.Errorf("operation %d: got %v", index, err)
+		}
+	}
+	if !reflect.DeepEqual(released, []string{"a", "c"}) {
+		t.Errorf("released %v", released)
+	}
+	nodes := (&models.NodeBeans{}).New()
+	if err := store.GetAll(&models.NodeBean{}, nodes); err != nil || !reflect.DeepEqual(names(nodes), []string{"b"}) {
+		t.Errorf("left %v %v", names(nodes), err)
+	}
+}
+
+func TestBulkDeleteReleaseFailure(t *testing.T) {
+	beans := []*models.NodeBean{node("a", "", nil), node("b", "", nil)}
+	store := memoryStore(t, beans...)
+	defer store.PreDestroy("store")
+	crud := &SqlCrudBusiness{Store: store, Events: (&winter.EventBus{}).New()}
+	failure := errors.New("link failure")
+	operations := make([]*Operation, 0)
+	for index, bean := range beans {
+		var err error
+		if index == 1 {
+			err = failure
+		}
+		operations = append(operations, &Operation{Op: "delete", Entity: bean.Copy(), release: func(context.Context, models.IPersistent) error {
+			return err
+		}})
+	}
+	// links are released, even a non atomic bulk is rolled back
+	if err := crud.Bulk(context.Background(), operations, false); err != failure {
+		t.Fatalf("got %v", err)
+	}
+	nodes := (&models.NodeBeans{}).New()
+	if err := store.GetAll(&models.NodeBean{}, nodes); err != nil || len(nodes.Get()) != 2 {
+		t.Errorf("left %v %v", names(nodes), err)
+	}
+}
+
+// nodeAPI a node API over this store
+func nodeAPI(store *Store) *API {
+	crud := &SqlCrudBusiness{Store: store, Events: (&winter.EventBus{}).New()}
+	return &API{SQLCrudBusiness: crud, Factory: func() models.IPersistent { return (&models.NodeBean{}).New() }, BulkMaxOperations: 10}
+}
+
+// mixedBulk create, update and upsert lines, then an update of a missing
+// node
+func mixedBulk(id string) string {
+	return `{"op":"create","body":{"name":"created"}}
+{"op":"update","id":"` + id + `","body":{"name":"updated"}}
+{"op":"upsert","id":"upserted","body":{"name":"upserted"}}
+{"op":"update","id":"missing","body":{"name":"missing"}}`
+}
+
+// stored node names, sorted
+func stored(t *testing.T, store *Store) []string {
+	nodes := (&models.NodeBeans{}).New()
+	if err := store.GetAll(&models.NodeBean{}, nodes); err != nil {
+		t.Fatal(err)
+	}
+	result := names(nodes)
+	sort.Strings(result)
+	return result
+}
+
+// statuses of these reports
+func statuses(reports []Report) []int {
+	result := make([]int, 0)
+	for _, report := range reports {
+		result = append(result, report.Status)
+	}
+	return result
+}
+
+func TestBulkAtomic(t *testing.T) {
+	bean := node("a", "", nil)
+	store := memoryStore(t, bean)
+	defer store.PreDestroy("store")
+	reports, status, err := nodeAPI(store).HandlerBulk(context.Background(), mixedBulk(bean.ID), true)
+	if err != nil || status != http.StatusNotFound {
+		t.Fatalf("got %d %v", status, err)
+	}
+	if got := statuses(reports); !reflect.DeepEqual(got, []int{424, 424, 424, 404}) {
+		t.Errorf("reports %v", got)
+	}
+	if reports[0].ID != "" {
+		t.Errorf("rolled back create reports id %s", reports[0].ID)
+	}
+	if got := stored(t, store); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("left %v", got)
+	}
+}
+
+func TestBulkNotAtomic(t *testing.T) {
+	bean := node("a", "", nil)
+	store := memoryStore(t, bean)
+	defer store.PreDestroy("store")
+	reports, status, err := nodeAPI(store).HandlerBulk(context.Background(), mixedBulk(bean.ID), false)
+	if err != nil || status != http.StatusMultiStatus {
+		t.Fatalf("got %d %v", status, err)
+	}
+	if got := statuses(reports); !reflect.DeepEqual(got, []int{201, 200, 201, 404}) {
+		t.Errorf("reports %v", got)
+	}
+	if reports[3].Error == nil || reports[3].Error.Status != http.StatusNotFound {
+		t.Errorf("missing node reports %+v", reports[3].Error)
+	}
+	if got := stored(t, store); !reflect.DeepEqual(got, []string{"created", "updated", "upserted"}) {
+		t.Errorf("left %v", got)
+	}
+}
+
+func TestBulkLimits(t *testing.T) {
+	api := nodeAPI(nil)
+	api.BulkMaxOperations, api.BulkMaxBytes = 2, 128
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"too many operations", `[{"op":"delete","id":"1"},{"op":"delete","id":"2"},{"op":"delete","id":"3"}]`, http.StatusUnprocessableEntity},
+		{"too large", `[{"op":"create","body":{"name":"` + strings.Repeat("x", 128) + `"}}]`, http.StatusRequestEntityTooLarge},
+	}
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest("POST", "/api/node/_bulk", bytes.NewBufferString(test.body))
+		c.Params = gin.Params{{Key: "id", Value: BulkID}}
+		api.HandlerStaticPostByID()(c)
+		if recorder.Code != test.status {
+			t.Errorf("%s: got %d %s", test.name, recorder.Code, recorder.Body)
+		}
+	}
+}
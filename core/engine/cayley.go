@@ -44,6 +44,11 @@ import (
 	"github.com/yroffin/go-boot-sqllite/core/winter"
 )
 
+const (
+	// LinkHREF link name of edges between resources
+	LinkHREF = "HREF"
+)
+
 // Graph internal members
 type Graph struct {
 	// members
@@ -51,12 +56,23 @@ type Graph struct {
 	// Store SQL lite
 	store *graph.Handle
 	// Db path
-	DbPath string
+	DbPath string `@value:"graph.path" @default:"./cayley.db"`
+	// Batch count of quads removed by a single transaction of Clear
+	Batch int `@value:"graph.batch" @default:"1000"`
+	// journal of a graph bound to a transaction, see Transaction
+	journal *[]change
+}
+
+// change applied to the graph, undone in reverse order by a failed
+// transaction
+type change struct {
+	quad  quad.Quad
+	added bool
 }
 
 // New constructor
-func (p *Graph) New(dbpath string) IGraphStore {
-	bean := Graph{Service: &winter.Service{Bean: &winter.Bean{}}, DbPath: dbpath}
+func (p *Graph) New() IGraphStore {
+	bean := Graph{Service: &winter.Service{Bean: &winter.Bean{}}}
 	return &bean
 }
 
@@ -67,6 +83,9 @@ func (p *Graph) Init() error {
 
 // PostConstruct this bean
 func (p *Graph) PostConstruct(name string) error {
+	if p.Batch < 1 {
+		p.Batch = 1
+	}
 	// Initialize the database
 	graph.InitQuadStore("bolt", p.DbPath, nil)
 
@@ -76,6 +95,7 @@ func (p *Graph) PostConstruct(name string) error {
 		log.WithFields(log.Fields{
 			"error": err,
 		}).Error("PostConstruct")
+		return err
 	}
 	p.store = database
 
@@ -87,19 +107,129 @@ func (p *Graph) Validate(name string) error {
 	return nil
 }
 
+// PreDestroy close this database
+func (p *Graph) PreDestroy(name string) error {
+	if p.store == nil {
+		return nil
+	}
+	err := p.store.Close()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"path":  p.DbPath,
+			"error": err,
+		}).Error("PreDestroy")
+	}
+	return err
+}
+
 // Clear Init this bean
 func (p *Graph) Clear() error {
+	batch := make([]quad.Quad, 0, p.Batch)
 	it := p.store.QuadsAllIterator()
+	defer it.Close()
 	for it.Next(context.Background()) {
-		qu := p.store.Quad(it.Result())
+		batch = append(batch, p.store.Quad(it.Result()))
+		if len(batch) >= p.Batch {
+			if err := p.remove(batch...); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		return p.remove(batch...)
+	}
+	return nil
+}
+
+// Transaction run fn with a graph journaling its changes, they are undone
+// if fn fails; the graph has no real transaction so this is a compensating
+// log, concurrent writers may see changes before they are undone. Nested
+// transactions undo their own changes only
+func (p *Graph) Transaction(fn func(IGraphStore) error) error {
+	journal := make([]change, 0)
+	bound := *p
+	bound.journal = &journal
+	if err := fn(&bound); err != nil {
+		p.undo(journal)
+		return err
+	}
+	if p.journal != nil {
+		*p.journal = append(*p.journal, journal...)
+	}
+	return nil
+}
+
+// undo these changes, last one first
+func (p *Graph) undo(journal []change) {
+	for index := len(journal) - 1; index >= 0; index-- {
 		tx := cayley.NewTransaction()
-		tx.RemoveQuad(qu)
-		p.store.ApplyTransaction(tx)
+		if journal[index].added {
+			tx.RemoveQuad(journal[index].quad)
+		} else {
+			tx.AddQuad(journal[index].quad)
+		}
+		if err := p.store.ApplyTransaction(tx); err != nil {
+			log.WithFields(log.Fields{
+				"quad":  journal[index].quad,
+				"error": err,
+			}).Error("Undo")
+		}
+	}
+}
+
+// add this quad, journaled in a transaction
+func (p *Graph) add(qu quad.Quad) error {
+	if err := p.store.AddQuad(qu); err != nil {
+		return err
+	}
+	if p.journal != nil {
+		*p.journal = append(*p.journal, change{quad: qu, added: true})
 	}
+	return nil
+}
 
+// remove these quads in a single transaction, journaled in a transaction
+func (p *Graph) remove(quads ...quad.Quad) error {
+	tx := cayley.NewTransaction()
+	for _, qu := range quads {
+		tx.RemoveQuad(qu)
+	}
+	if err := p.store.ApplyTransaction(tx); err != nil {
+		return err
+	}
+	if p.journal != nil {
+		for _, qu := range quads {
+			*p.journal = append(*p.journal, change{quad: qu, added: false})
+		}
+	}
 	return nil
 }
 
+// lookup quads by one of their nodes in the index of the quad store, a
+// link instance is the only one of its predicate
+func (p *Graph) lookup(direction quad.Direction, node string) []quad.Quad {
+	quads := make([]quad.Quad, 0)
+	ref := p.store.ValueOf(quad.String(node))
+	if ref == nil {
+		return quads
+	}
+	it := p.store.QuadIterator(direction, ref)
+	defer it.Close()
+	for it.Next(context.Background()) {
+		quads = append(quads, p.store.Quad(it.Result()))
+	}
+	return quads
+}
+
+// predicate node of a link instance
+func (p *Graph) predicate(data models.IEdgeBean) string {
+	if len(data.GetLink()) == 0 {
+		return LinkHREF + ":" + data.GetInstance()
+	}
+	return data.GetLink() + ":" + data.GetInstance()
+}
+
 type quadCayley struct {
 	subjectID   string
 	subject     string
@@ -228,18 +358,19 @@ func (p *Graph) CreateLink(data models.IEdgeBean) error {
 		"json": string(jsonData),
 		"quad": quad,
 	}).Info("Create")
-	p.store.AddQuad(quad)
-	return nil
+	return p.add(quad)
 }
 
 // UpdateLink in graph db
 func (p *Graph) UpdateLink(data models.IEdgeBean) error {
-	// find existing link and remove it
-	var query = `g.V('/` + data.GetSource() + `/` + data.GetSourceID() + `').As('source').Out(null, 'edge').As('target').Labels().As('label').All()`
-	results, _ := p.QueryGizmo(query, "")
-	for _, v := range results {
-		if v.GetInstance() == data.GetInstance() {
-			p.DeleteLink(data)
+	// find existing link of this source and remove it
+	var subject = "/" + data.GetSource() + "/" + data.GetSourceID()
+	for _, qu := range p.lookup(quad.Predicate, p.predicate(data)) {
+		if qu.Subject.Native().(string) != subject {
+			continue
+		}
+		if err := p.remove(qu); err != nil {
+			return err
 		}
 	}
 
@@ -254,28 +385,23 @@ func (p *Graph) UpdateLink(data models.IEdgeBean) error {
 		"json": string(jsonData),
 		"quad": quad,
 	}).Info("Update")
-	p.store.AddQuad(quad)
-	return nil
+	return p.add(quad)
 }
 
 // DeleteLink this persistent bean
 func (p *Graph) DeleteLink(toDelete models.IEdgeBean) error {
-	it := p.store.QuadsAllIterator()
-	for it.Next(context.Background()) {
-		qu := p.store.Quad(it.Result())
-		if strings.HasSuffix(qu.Predicate.Native().(string), ":"+toDelete.GetInstance()) {
-			log.WithFields(log.Fields{
-				"subject":   qu.Subject.Native(),
-				"predicate": qu.Predicate.Native(),
-				"object":    qu.Object.Native(),
-			}).Info("Remove")
-			tx := cayley.NewTransaction()
-			tx.RemoveQuad(qu)
-			p.store.ApplyTransaction(tx)
-		}
+	quads := p.lookup(quad.Predicate, p.predicate(toDelete))
+	for _, qu := range quads {
+		log.WithFields(log.Fields{
+			"subject":   qu.Subject.Native(),
+			"predicate": qu.Predicate.Native(),
+			"object":    qu.Object.Native(),
+		}).Info("Remove")
 	}
-
-	return nil
+	if len(quads) == 0 {
+		return nil
+	}
+	return p.remove(quads...)
 }
 
 // TruncateLink method
@@ -301,6 +427,87 @@ func (p *Graph) GetAllLink(model string, id string, array *[]models.IEdgeBean, t
 	return nil
 }
 
+// GetAllLinkTo all links whose object is this persistent bean
+func (p *Graph) GetAllLinkTo(model string, id string, array *[]models.IEdgeBean) error {
+	for _, qu := range p.lookup(quad.Object, "/"+model+"/"+id) {
+		data := models.EdgeBean{}
+		if err := json.Unmarshal([]byte(qu.Label.Native().(string)), &data); err != nil {
+			return err
+		}
+		data.SetInstance(data.GetID())
+		*array = append(*array, &data)
+	}
+	return nil
+}
+
+// Out all links from these nodes, "/entity/id", in a single gizmo query
+func (p *Graph) Out(nodes []string) ([]models.IEdgeBean, error) {
+	if len(nodes) == 0 {
+		return make([]models.IEdgeBean, 0), nil
+	}
+	literals := make([]string, len(nodes))
+	for index, node := range nodes {
+		literals[index] = literal(node)
+	}
+	var query = `g.V(` + strings.Join(literals, ", ") + `).As('source').Out(null, 'edge').As('target').Labels().As('label').All()`
+	return p.QueryGizmo(query, "")
+}
+
+// literal javascript string of this value, json strings are valid ones
+func literal(value string) string {
+	text, _ := json.Marshal(value)
+	return string(text)
+}
+
+// Query run this gizmo query on the quad store only, so that it can not
+// write, until ctx is done or limit results are read; each result maps its
+// tags to their native values, and an emitted value to "value"
+func (p *Graph) Query(ctx context.Context, text string, limit int) ([]map[string]interface{}, error) {
+	session := gizmo.NewSession(p.store.QuadStore)
+	ctx, cancel := context.WithCancel(ctx)
+	c := make(chan query.Result, 1)
+	go session.Execute(ctx, text, c, limit)
+	defer func() {
+		// stop the session and drain it aside, a script may take a while
+		// to notice
+		cancel()
+		go func() {
+			for range c {
+			}
+		}()
+	}()
+
+	resultSet := make([]map[string]interface{}, 0)
+	for len(resultSet) < limit {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case res, ok := <-c:
+			if !ok {
+				return resultSet, nil
+			}
+			if err := res.Err(); err != nil {
+				return nil, err
+			}
+			result, ok := res.(*gizmo.Result)
+			if !ok {
+				continue
+			}
+			tags := make(map[string]interface{})
+			for tag, value := range result.Tags {
+				if name := p.store.NameOf(value); name != nil {
+					tags[tag] = name.Native()
+				}
+			}
+			if result.Val != nil {
+				tags["value"] = result.Val
+			}
+			resultSet = append(resultSet, tags)
+		}
+	}
+	return resultSet, nil
+}
+
 // QueryGizmo query gizmo
 func (p *Graph) QueryGizmo(text string, tag string) ([]models.IEdgeBean, error) {
 	session := gizmo.NewSession(p.store)
@@ -0,0 +1,96 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/yroffin/go-boot-sqllite/core/models"
+)
+
+// quadCount all quads of this graph
+func quadCount(graph *Graph) int {
+	count := 0
+	it := graph.store.QuadsAllIterator()
+	defer it.Close()
+	for it.Next(context.Background()) {
+		count++
+	}
+	return count
+}
+
+// parentLink a parent link between two nodes
+func parentLink(source string, target string) models.IEdgeBean {
+	return (&models.EdgeBean{}).New("NodeBean", source, "NodeBean", target, "parent")
+}
+
+func TestLinkLookup(t *testing.T) {
+	graph := memoryGraph(t)
+	first, second := parentLink("1", "2"), parentLink("1", "3")
+	for _, link := range []models.IEdgeBean{first, second} {
+		if err := graph.CreateLink(link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// each instance has its own predicate
+	if quads := graph.lookup(quad.Predicate, graph.predicate(first)); len(quads) != 1 || quads[0].Object.Native() != "/NodeBean/2" {
+		t.Errorf("got %v", quads)
+	}
+	if quads := graph.lookup(quad.Predicate, "parent:missing"); len(quads) != 0 {
+		t.Errorf("got %v", quads)
+	}
+	if err := graph.DeleteLink(first); err != nil {
+		t.Fatal(err)
+	}
+	if linked(graph, first) || !linked(graph, second) || quadCount(graph) != 1 {
+		t.Errorf("left %d quads", quadCount(graph))
+	}
+	// an update replaces the instance by a new one
+	previous := graph.predicate(second)
+	second.(*models.EdgeBean).TargetID = "4"
+	if err := graph.UpdateLink(second); err != nil {
+		t.Fatal(err)
+	}
+	if len(graph.lookup(quad.Predicate, previous)) != 0 || !linked(graph, second) || quadCount(graph) != 1 {
+		t.Errorf("left %d quads", quadCount(graph))
+	}
+}
+
+func TestClear(t *testing.T) {
+	graph := memoryGraph(t)
+	graph.Batch = 2
+	for index := 0; index < 5; index++ {
+		if err := graph.CreateLink(parentLink("1", string(rune('a'+index)))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// removed in batches, the last one partial
+	if err := graph.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if count := quadCount(graph); count != 0 {
+		t.Errorf("left %d quads", count)
+	}
+}
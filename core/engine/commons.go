@@ -32,4 +32,9 @@ type IHttpContext interface {
 	Query(key string) string
 	GetQuery(key string) (string, bool)
 	GetRawData() ([]byte, error)
+	ContentType() string
+	GetHeader(key string) string
+	// Request attributes, used by request scoped beans
+	Set(key string, value interface{})
+	Get(key string) (interface{}, bool)
 }
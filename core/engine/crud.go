@@ -23,6 +23,8 @@
 package engine
 
 import (
+	"context"
+
 	"github.com/yroffin/go-boot-sqllite/core/models"
 	"github.com/yroffin/go-boot-sqllite/core/winter"
 )
@@ -32,11 +34,17 @@ type ICrudBusiness interface {
 	winter.IBean
 	// Relationnal data
 	GetAll(models.IPersistent, models.IPersistents) (models.IPersistents, error)
+	Find(models.IPersistent, models.IPersistents, *Filter) (models.IPersistents, error)
+	Select(models.IPersistent, models.IPersistents, *Query) (models.IPersistents, *Page, error)
 	Get(models.IPersistent) (models.IPersistent, error)
 	Create(models.IPersistent) (models.IPersistent, error)
 	Update(models.IPersistent) (models.IPersistent, error)
+	Upsert(models.IPersistent) (models.IPersistent, bool, error)
 	Delete(models.IPersistent) (models.IPersistent, error)
-	Patch(models.IPersistent) (models.IPersistent, error)
+	Patch(models.IPersistent, Patcher) (models.IPersistent, error)
+	Bulk(context.Context, []*Operation, bool) error
+	// Work run a function in a unit of work, see Unit
+	Work(context.Context, func(context.Context) error) error
 	Clear([]string) error
 	Statistics() ([]IStats, error)
 }
@@ -50,6 +58,12 @@ type ILinkBusiness interface {
 	DeleteLink(toCreate models.IEdgeBean) (models.IEdgeBean, error)
 	PatchLink(toPatch models.IEdgeBean) (models.IEdgeBean, error)
 	GetAllLink(model string, id string, toGets []models.IEdgeBean, targetType string) ([]models.IEdgeBean, error)
+	GetAllLinkTo(model string, id string, toGets []models.IEdgeBean) ([]models.IEdgeBean, error)
+	Traverse(traversal *Traversal) (*Branch, error)
+	// Query run a read only gizmo query
+	Query(ctx context.Context, query *GraphQuery) (*GraphResult, error)
+	// Transaction run fn with links undone if it fails
+	Transaction(fn func(ILinkBusiness) error) error
 	// Models admin
 	Clear() error
 	All() ([]IQuad, error)
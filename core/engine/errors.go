@@ -0,0 +1,254 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// RequestIDKey request attribute of the X-Request-Id
+	RequestIDKey = "winter.request.id"
+)
+
+// Kind classify data errors, each kind has its http status
+type Kind int
+
+const (
+	// Internal unexpected failure, 500
+	Internal Kind = iota
+	// NotFound unknown entity, 404
+	NotFound
+	// Conflict entity state forbids this change, 409
+	Conflict
+	// Validation malformed or invalid entity, 422
+	Validation
+	// PreconditionFailed entity version does not match If-Match, 412
+	PreconditionFailed
+	// Unavailable resource temporarily exhausted, 503
+	Unavailable
+	// Unauthorized missing or wrong credentials, 401
+	Unauthorized
+	// Timeout work cancelled once its deadline exceeded, 504
+	Timeout
+	// UnsupportedMediaType body content type not understood, 415
+	UnsupportedMediaType
+	// TooLarge body exceeding its limit, 413
+	TooLarge
+)
+
+const (
+	// TimeoutType problem type of Timeout errors, other problems are
+	// about:blank
+	TimeoutType = "urn:go-boot-sqllite:problem:timeout"
+)
+
+// Status http status of this kind
+func (k Kind) Status() int {
+	switch k {
+	case NotFound:
+		return http.StatusNotFound
+	case Conflict:
+		return http.StatusConflict
+	case Validation:
+		return http.StatusUnprocessableEntity
+	case PreconditionFailed:
+		return http.StatusPreconditionFailed
+	case Unavailable:
+		return http.StatusServiceUnavailable
+	case Unauthorized:
+		return http.StatusUnauthorized
+	case Timeout:
+		return http.StatusGatewayTimeout
+	case UnsupportedMediaType:
+		return http.StatusUnsupportedMediaType
+	case TooLarge:
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusInternalServerError
+}
+
+// String kind name
+func (k Kind) String() string {
+	switch k {
+	case NotFound:
+		return "NotFound"
+	case Conflict:
+		return "Conflict"
+	case Validation:
+		return "Validation"
+	case PreconditionFailed:
+		return "PreconditionFailed"
+	case Unavailable:
+		return "Unavailable"
+	case Unauthorized:
+		return "Unauthorized"
+	case Timeout:
+		return "Timeout"
+	case UnsupportedMediaType:
+		return "UnsupportedMediaType"
+	case TooLarge:
+		return "TooLarge"
+	}
+	return "Internal"
+}
+
+// Error a typed error raised by stores, business beans or APIs
+type Error struct {
+	// Kind of error
+	Kind Kind
+	// Entity name involved
+	Entity string
+	// Message for clients
+	Message string
+	// Err cause, if any
+	Err error
+	// Violations of a Validation error, see Validate
+	Violations []Violation
+}
+
+// Error message
+func (e *Error) Error() string {
+	var message = e.Kind.String()
+	if len(e.Entity) > 0 {
+		message = message + " on " + e.Entity
+	}
+	if len(e.Message) > 0 {
+		message = message + ": " + e.Message
+	}
+	if e.Err != nil {
+		message = message + " (" + e.Err.Error() + ")"
+	}
+	return message
+}
+
+// Unwrap cause
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// KindOf get the kind of any error, typed errors may be wrapped, untyped
+// errors are internal
+func KindOf(err error) Kind {
+	var typed *Error
+	if errors.As(err, &typed) {
+		return typed.Kind
+	}
+	return Internal
+}
+
+// notFound error for this entity id
+func notFound(entity string, id string) error {
+	return &Error{Kind: NotFound, Entity: entity, Message: "no entity with id " + id}
+}
+
+// stale error for an entity changed since this version was read
+func stale(entity string, id string, version int64) error {
+	return &Error{Kind: PreconditionFailed, Entity: entity, Message: "entity " + id + " is not at version " + strconv.FormatInt(version, 10)}
+}
+
+// unknownTask error for a task no API declares
+func unknownTask(entity string, name string) error {
+	return &Error{Kind: NotFound, Entity: entity, Message: "no task " + name}
+}
+
+// conflict error for this entity
+func conflict(entity string, message string, err error) error {
+	return &Error{Kind: Conflict, Entity: entity, Message: message, Err: err}
+}
+
+// invalid error for this entity
+func invalid(entity string, message string, err error) error {
+	return &Error{Kind: Validation, Entity: entity, Message: message, Err: err}
+}
+
+// unsupported content type for this entity
+func unsupported(entity string, contentType string) error {
+	return &Error{Kind: UnsupportedMediaType, Entity: entity, Message: "unsupported content type '" + contentType + "'"}
+}
+
+// internal error for this entity, typed errors, even wrapped, are kept
+// as is
+func internal(entity string, message string, err error) error {
+	var typed *Error
+	if errors.As(err, &typed) {
+		return err
+	}
+	return &Error{Kind: Internal, Entity: entity, Message: message, Err: err}
+}
+
+// Problem RFC 7807 problem details
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Entity    string `json:"entity,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+	// Violations every failing field of a Validation problem
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// NewProblem problem details of this error, entity is used when the error
+// does not name one
+func NewProblem(entity string, err error) Problem {
+	var kind = KindOf(err)
+	problem := Problem{Type: "about:blank", Title: http.StatusText(kind.Status()), Status: kind.Status(), Entity: entity}
+	if kind == Timeout {
+		problem.Type = TimeoutType
+	}
+	var typed *Error
+	if errors.As(err, &typed) {
+		problem.Detail = typed.Message
+		// internal causes are only logged
+		if kind != Internal && typed.Err != nil {
+			problem.Detail = problem.Detail + ": " + typed.Err.Error()
+		}
+		if len(typed.Entity) > 0 {
+			problem.Entity = typed.Entity
+		}
+		problem.Violations = typed.Violations
+	}
+	return problem
+}
+
+// WriteProblem write this error as problem details, entity is used when
+// the error does not name one
+func WriteProblem(c IHttpContext, entity string, err error) {
+	problem := NewProblem(entity, err)
+	if id, ok := c.Get(RequestIDKey); ok {
+		problem.RequestID, _ = id.(string)
+	}
+	log.WithFields(log.Fields{
+		"status":  problem.Status,
+		"entity":  problem.Entity,
+		"request": problem.RequestID,
+		"error":   err,
+	}).Warn("Problem")
+	c.Header("Content-type", "application/problem+json")
+	c.IndentedJSON(problem.Status, problem)
+}
@@ -0,0 +1,105 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestKindStatus(t *testing.T) {
+	tests := []struct {
+		kind   Kind
+		status int
+		name   string
+	}{
+		{Internal, http.StatusInternalServerError, "Internal"},
+		{NotFound, http.StatusNotFound, "NotFound"},
+		{Conflict, http.StatusConflict, "Conflict"},
+		{Validation, http.StatusUnprocessableEntity, "Validation"},
+		{PreconditionFailed, http.StatusPreconditionFailed, "PreconditionFailed"},
+		{Unavailable, http.StatusServiceUnavailable, "Unavailable"},
+		{Unauthorized, http.StatusUnauthorized, "Unauthorized"},
+		{Timeout, http.StatusGatewayTimeout, "Timeout"},
+		{UnsupportedMediaType, http.StatusUnsupportedMediaType, "UnsupportedMediaType"},
+		{TooLarge, http.StatusRequestEntityTooLarge, "TooLarge"},
+	}
+	for _, test := range tests {
+		if test.kind.Status() != test.status || test.kind.String() != test.name {
+			t.Errorf("%s: got %d %s", test.name, test.kind.Status(), test.kind.String())
+		}
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	violations := []Violation{{Field: "type", Rule: "required", Message: "is required"}}
+	typed := &Error{Kind: Validation, Entity: "NodeBean", Message: "invalid fields type", Violations: violations}
+	tests := []struct {
+		name       string
+		err        error
+		kind       Kind
+		violations []Violation
+	}{
+		{"nil", nil, Internal, nil},
+		{"untyped", errors.New("failure"), Internal, nil},
+		{"typed", typed, Validation, violations},
+		{"wrapped", fmt.Errorf("interceptor: %w", typed), Validation, violations},
+		{"wrapped twice", fmt.Errorf("task: %w", fmt.Errorf("interceptor: %w", notFound("NodeBean", "1"))), NotFound, nil},
+		{"internal keeps wrapped", internal("NodeBean", "failure", fmt.Errorf("interceptor: %w", typed)), Validation, violations},
+		{"internal wraps untyped", internal("NodeBean", "failure", errors.New("failure")), Internal, nil},
+	}
+	for _, test := range tests {
+		if kind := KindOf(test.err); kind != test.kind {
+			t.Errorf("%s: got %v", test.name, kind)
+		}
+		if test.err == nil {
+			continue
+		}
+		problem := NewProblem("Entity", test.err)
+		if problem.Status != test.kind.Status() || !reflect.DeepEqual(problem.Violations, test.violations) {
+			t.Errorf("%s: got %+v", test.name, problem)
+		}
+	}
+}
+
+func TestProblemDetail(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		detail string
+		entity string
+	}{
+		{"cause", invalid("NodeBean", "malformed body", errors.New("eof")), "malformed body: eof", "NodeBean"},
+		{"internal cause is hidden", internal("", "database failure", errors.New("disk")), "database failure", "Entity"},
+		{"untyped", errors.New("failure"), "", "Entity"},
+	}
+	for _, test := range tests {
+		problem := NewProblem("Entity", test.err)
+		if problem.Detail != test.detail || problem.Entity != test.entity || problem.Type != "about:blank" {
+			t.Errorf("%s: got %+v", test.name, problem)
+		}
+	}
+}
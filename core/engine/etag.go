@@ -0,0 +1,91 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/yroffin/go-boot-sqllite/core/models"
+)
+
+// ETag strong entity tag of this bean, its quoted stored version, empty when
+// the bean is not versioned
+func ETag(entity interface{}) string {
+	if versioned, ok := entity.(models.IVersioned); ok && versioned.GetVersion() > 0 {
+		return strconv.Quote(strconv.FormatInt(versioned.GetVersion(), 10))
+	}
+	return ""
+}
+
+// tags split an If-Match or If-None-Match header
+func tags(header string) []string {
+	result := make([]string, 0)
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); len(tag) > 0 {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// IfMatch expected version of an If-Match header, 0 without header or with *,
+// weak and unknown tags never match
+func IfMatch(entity string, header string) (int64, error) {
+	values := tags(header)
+	if len(values) == 0 || (len(values) == 1 && values[0] == "*") {
+		return 0, nil
+	}
+	if len(values) > 1 {
+		return 0, invalid(entity, "If-Match accepts a single entity tag", nil)
+	}
+	value, err := strconv.Unquote(values[0])
+	if err == nil {
+		if version, err := strconv.ParseInt(value, 10, 64); err == nil && version > 0 {
+			return version, nil
+		}
+	}
+	return 0, &Error{Kind: PreconditionFailed, Entity: entity, Message: "If-Match " + values[0] + " does not match"}
+}
+
+// IfNoneMatch true if this tag is one of an If-None-Match header, using the
+// weak comparison
+func IfNoneMatch(header string, tag string) bool {
+	if len(tag) == 0 {
+		return false
+	}
+	for _, value := range tags(header) {
+		if value == "*" || strings.TrimPrefix(value, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// expect this version of a bean before changing it, 0 for any version
+func expect(entity models.IPersistent, version int64) models.IPersistent {
+	if versioned, ok := entity.(models.IVersioned); ok {
+		versioned.SetVersion(version)
+	}
+	return entity
+}
@@ -0,0 +1,168 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/yroffin/go-boot-sqllite/core/models"
+)
+
+func TestETag(t *testing.T) {
+	tests := []struct {
+		entity interface{}
+		tag    string
+	}{
+		{&models.NodeBean{Version: 3}, `"3"`},
+		{&models.NodeBean{}, ``},
+		{&models.EdgeBean{}, ``},
+	}
+	for _, test := range tests {
+		if tag := ETag(test.entity); tag != test.tag {
+			t.Errorf("%#v: got %s, expected %s", test.entity, tag, test.tag)
+		}
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		version int64
+		kind    Kind
+		err     bool
+	}{
+		{``, 0, 0, false},
+		{`*`, 0, 0, false},
+		{` "12" `, 12, 0, false},
+		{`"1", "2"`, 0, Validation, true},
+		{`W/"1"`, 0, PreconditionFailed, true},
+		{`"x"`, 0, PreconditionFailed, true},
+		{`"0"`, 0, PreconditionFailed, true},
+		{`1`, 0, PreconditionFailed, true},
+	}
+	for _, test := range tests {
+		version, err := IfMatch("NodeBean", test.header)
+		if test.err != (err != nil) || (err != nil && KindOf(err) != test.kind) || version != test.version {
+			t.Errorf("%s: got %d %v", test.header, version, err)
+		}
+	}
+}
+
+func TestIfNoneMatch(t *testing.T) {
+	tests := []struct {
+		header string
+		tag    string
+		match  bool
+	}{
+		{``, `"1"`, false},
+		{`"1"`, `"1"`, true},
+		{`W/"1"`, `"1"`, true},
+		{`"2", "1"`, `"1"`, true},
+		{`"2"`, `"1"`, false},
+		{`*`, `"1"`, true},
+		{`*`, ``, false},
+	}
+	for _, test := range tests {
+		if match := IfNoneMatch(test.header, test.tag); match != test.match {
+			t.Errorf("%s against %s: got %v", test.header, test.tag, match)
+		}
+	}
+}
+
+func TestStoreVersions(t *testing.T) {
+	bean := node("temp", "sensor", nil)
+	store := memoryStore(t, bean)
+	defer store.PreDestroy("store")
+	if bean.Version != 1 {
+		t.Fatalf("created at version %d", bean.Version)
+	}
+	tests := []struct {
+		name     string
+		id       string
+		expected int64
+		// version once updated, 0 on error
+		version int64
+		kind    Kind
+	}{
+		{"any version", bean.ID, 0, 2, 0},
+		{"expected version", bean.ID, 2, 3, 0},
+		{"stale version", bean.ID, 2, 0, PreconditionFailed},
+		{"missing", "missing", 0, 0, NotFound},
+		{"missing with version", "missing", 1, 0, PreconditionFailed},
+	}
+	for _, test := range tests {
+		update := node("temp", "sensor", nil)
+		update.Version = test.expected
+		err := store.Update(test.id, update)
+		if test.version == 0 {
+			if KindOf(err) != test.kind {
+				t.Errorf("%s: expected %v, got %v", test.name, test.kind, err)
+			}
+			continue
+		}
+		if err != nil || update.Version != test.version {
+			t.Errorf("%s: got version %d %v", test.name, update.Version, err)
+		}
+	}
+	stored := (&models.NodeBean{}).New().(*models.NodeBean)
+	if err := store.Get(bean.ID, stored); err != nil || stored.Version != 3 {
+		t.Errorf("stored version %d %v", stored.Version, err)
+	}
+}
+
+func TestStoreUpsert(t *testing.T) {
+	store := memoryStore(t)
+	defer store.PreDestroy("store")
+	for index, expected := range []struct {
+		created bool
+		version int64
+	}{{true, 1}, {false, 2}} {
+		bean := node("temp", "sensor", nil)
+		created, err := store.Upsert("fixed", bean)
+		if err != nil || created != expected.created || bean.Version != expected.version {
+			t.Errorf("upsert %d: got %v %d %v", index, created, bean.Version, err)
+		}
+	}
+}
+
+func TestStoreUpdateInTransaction(t *testing.T) {
+	bean := node("temp", "sensor", nil)
+	store := memoryStore(t, bean)
+	defer store.PreDestroy("store")
+	rollback := errors.New("rollback")
+	err := store.Transaction(func(bound IDataStore) error {
+		update := node("hot", "sensor", nil)
+		if err := bound.Update(bean.ID, update); err != nil || update.Version != 2 {
+			t.Errorf("got version %d %v", update.Version, err)
+		}
+		return rollback
+	})
+	if err != rollback {
+		t.Fatal(err)
+	}
+	stored := (&models.NodeBean{}).New().(*models.NodeBean)
+	if err := store.Get(bean.ID, stored); err != nil || stored.Name != "temp" || stored.Version != 1 {
+		t.Errorf("rolled back to %+v %v", stored, err)
+	}
+}
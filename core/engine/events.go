@@ -0,0 +1,57 @@
+// Package business for business interface
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"github.com/yroffin/go-boot-sqllite/core/models"
+)
+
+// EntityCreated published once an entity is created
+type EntityCreated struct {
+	Entity models.IPersistent
+}
+
+// EntityUpdated published once an entity is updated or patched
+type EntityUpdated struct {
+	Entity models.IPersistent
+}
+
+// EntityDeleted published once an entity is deleted
+type EntityDeleted struct {
+	Entity models.IPersistent
+}
+
+// LinkCreated published once a link is created
+type LinkCreated struct {
+	Link models.IEdgeBean
+}
+
+// LinkUpdated published once a link is updated
+type LinkUpdated struct {
+	Link models.IEdgeBean
+}
+
+// LinkDeleted published once a link is deleted
+type LinkDeleted struct {
+	Link models.IEdgeBean
+}
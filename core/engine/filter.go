@@ -0,0 +1,171 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+)
+
+const (
+	// maxFilterDepth limit and/or nesting
+	maxFilterDepth = 16
+)
+
+var (
+	// field path inside entity json, extended fields as extended.name
+	filterField = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z0-9_]+)*$`)
+	// filterOps supported comparison operators
+	filterOps = map[string]string{
+		"eq":   "=",
+		"ne":   "<>",
+		"lt":   "<",
+		"le":   "<=",
+		"gt":   ">",
+		"ge":   ">=",
+		"in":   "IN",
+		"like": "LIKE",
+	}
+)
+
+// Filter a filter expression on entity json fields, either a node
+// with And or Or children, or a comparison of Field with Value
+//
+//	{"and":[{"field":"type","op":"eq","value":"sensor"},
+//	        {"or":[{"field":"extended.room","op":"in","value":["kitchen","hall"]},
+//	               {"field":"name","op":"like","value":"temp%"}]}]}
+//
+// A plain object like {"type":"sensor","extended.room":"kitchen"} is
+// read as an equality on each field
+type Filter struct {
+	And   []*Filter   `json:"and,omitempty"`
+	Or    []*Filter   `json:"or,omitempty"`
+	Field string      `json:"field,omitempty"`
+	Op    string      `json:"op,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// ParseFilter decode and check a filter, an empty body match all
+func ParseFilter(body string) (*Filter, error) {
+	if len(body) == 0 {
+		return &Filter{}, nil
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &raw); err != nil {
+		return nil, err
+	}
+	filter := &Filter{}
+	_, and := raw["and"]
+	_, or := raw["or"]
+	_, field := raw["field"]
+	if and || or || field {
+		if err := json.Unmarshal([]byte(body), filter); err != nil {
+			return nil, err
+		}
+	} else {
+		// shorthand, sorted for a stable statement
+		keys := make([]string, 0, len(raw))
+		for key := range raw {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			var value interface{}
+			if err := json.Unmarshal(raw[key], &value); err != nil {
+				return nil, err
+			}
+			filter.And = append(filter.And, &Filter{Field: key, Op: "eq", Value: value})
+		}
+	}
+	return filter, filter.check(0)
+}
+
+// check this filter recursively
+func (f *Filter) check(depth int) error {
+	if depth > maxFilterDepth {
+		return errors.New("filter is nested too deeply")
+	}
+	if f == nil {
+		return errors.New("filter is empty")
+	}
+	if len(f.Field) == 0 {
+		if len(f.And) > 0 && len(f.Or) > 0 {
+			return errors.New("filter can not mix and/or in the same node")
+		}
+		for _, child := range append(f.And, f.Or...) {
+			if err := child.check(depth + 1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(f.And) > 0 || len(f.Or) > 0 {
+		return errors.New("filter on field " + f.Field + " can not have and/or children")
+	}
+	if !filterField.MatchString(f.Field) {
+		return errors.New("filter field " + f.Field + " is invalid")
+	}
+	if len(f.Op) == 0 {
+		f.Op = "eq"
+	}
+	if _, ok := filterOps[f.Op]; !ok {
+		return errors.New("filter operator " + f.Op + " is unknown")
+	}
+	switch f.Op {
+	case "in":
+		values, ok := f.Value.([]interface{})
+		if !ok || len(values) == 0 {
+			return errors.New("filter in on field " + f.Field + " needs a non empty array")
+		}
+		for _, value := range values {
+			if !scalar(value) || value == nil {
+				return errors.New("filter in on field " + f.Field + " needs scalar values")
+			}
+		}
+		return nil
+	case "like":
+		if _, ok := f.Value.(string); !ok {
+			return errors.New("filter like on field " + f.Field + " needs a string")
+		}
+	case "eq", "ne":
+	default:
+		if f.Value == nil {
+			return errors.New("filter " + f.Op + " on field " + f.Field + " needs a value")
+		}
+	}
+	if !scalar(f.Value) {
+		return errors.New("filter " + f.Op + " on field " + f.Field + " needs a scalar value")
+	}
+	return nil
+}
+
+// scalar check for json null, string, number or boolean
+func scalar(value interface{}) bool {
+	switch value.(type) {
+	case nil, string, float64, bool:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/yroffin/go-boot-sqllite/core/models"
+)
+
+// memoryStore an in memory store with a NodeBean table and these nodes
+func memoryStore(t *testing.T, nodes ...*models.NodeBean) *Store {
+	store := (&Store{}).New().(*Store)
+	store.DbPath = "file:" + t.Name() + "?mode=memory&cache=shared"
+	if err := store.open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.table("NodeBean"); err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range nodes {
+		if err := store.Create(node); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+// node a NodeBean with these extended properties
+func node(name string, typ string, extended map[string]interface{}) *models.NodeBean {
+	bean := (&models.NodeBean{}).New().(*models.NodeBean)
+	bean.Name, bean.Type = name, typ
+	bean.Extend(extended)
+	return bean
+}
+
+// names of these nodes, in their order
+func names(array models.IPersistents) []string {
+	result := make([]string, 0)
+	for _, bean := range array.Get() {
+		result = append(result, bean.(*models.NodeBean).Name)
+	}
+	return result
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		body string
+		// error message, empty for a valid filter
+		err string
+	}{
+		{``, ""},
+		{`{"type":"sensor","extended.room":"kitchen"}`, ""},
+		{`{"field":"name","value":null}`, ""},
+		{`{"and":[{"field":"a","op":"in","value":[1,"x",true]},{"or":[{"field":"b","op":"like","value":"x%"}]}]}`, ""},
+		{`{"field":`, "unexpected end of JSON input"},
+		{`{"and":[{"field":"a"}],"or":[{"field":"b"}]}`, "filter can not mix and/or in the same node"},
+		{`{"field":"a","and":[{"field":"b"}]}`, "filter on field a can not have and/or children"},
+		{`{"field":"a;drop","value":1}`, "filter field a;drop is invalid"},
+		{`{"field":"a","op":"regexp","value":1}`, "filter operator regexp is unknown"},
+		{`{"field":"a","op":"in","value":[]}`, "filter in on field a needs a non empty array"},
+		{`{"field":"a","op":"in","value":[null]}`, "filter in on field a needs scalar values"},
+		{`{"field":"a","op":"like","value":1}`, "filter like on field a needs a string"},
+		{`{"field":"a","op":"gt"}`, "filter gt on field a needs a value"},
+		{`{"field":"a","value":{"b":1}}`, "filter eq on field a needs a scalar value"},
+		{`{"and":[null]}`, "filter is empty"},
+		{strings.Repeat(`{"and":[`, 18) + `{"field":"a"}` + strings.Repeat(`]}`, 18), "filter is nested too deeply"},
+	}
+	for _, test := range tests {
+		_, err := ParseFilter(test.body)
+		var message = ""
+		if err != nil {
+			message = err.Error()
+		}
+		if message != test.err {
+			t.Errorf("%s: expected error %q, got %q", test.body, test.err, message)
+		}
+	}
+}
+
+func TestWhere(t *testing.T) {
+	tests := []struct {
+		body  string
+		where string
+		args  []interface{}
+	}{
+		{``, "", []interface{}{}},
+		{`{"type":"sensor"}`, "(json_extract(json, ?) = ?)", []interface{}{"$.type", "sensor"}},
+		{`{"field":"extended.room","value":null}`, "json_extract(json, ?) IS NULL", []interface{}{"$.extended.room"}},
+		{`{"field":"name","op":"ne","value":null}`, "json_extract(json, ?) IS NOT NULL", []interface{}{"$.name"}},
+		{`{"field":"name","op":"in","value":["a","b"]}`, "json_extract(json, ?) IN (?,?)", []interface{}{"$.name", "a", "b"}},
+		{`{"or":[{"field":"extended.level","op":"ge","value":2},{"and":[{"field":"name","op":"like","value":"t%"},{"field":"type","op":"ne","value":"x"}]}]}`,
+			"(json_extract(json, ?) >= ?) OR ((json_extract(json, ?) LIKE ?) AND (json_extract(json, ?) <> ?))",
+			[]interface{}{"$.extended.level", 2.0, "$.name", "t%", "$.type", "x"}},
+	}
+	store := &Store{}
+	for _, test := range tests {
+		filter, err := ParseFilter(test.body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		where, args := store.where(filter)
+		if where != test.where || !reflect.DeepEqual(args, test.args) {
+			t.Errorf("%s: got %s %v", test.body, where, args)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	store := memoryStore(t,
+		node("temp", "sensor", map[string]interface{}{"room": "kitchen", "level": 1}),
+		node("hygro", "sensor", map[string]interface{}{"room": "hall", "level": 5}),
+		node("light", "actuator", map[string]interface{}{"room": "kitchen"}),
+	)
+	defer store.PreDestroy("store")
+	tests := []struct {
+		body  string
+		names []string
+	}{
+		{``, []string{"hygro", "light", "temp"}},
+		{`{"type":"sensor"}`, []string{"hygro", "temp"}},
+		{`{"type":"sensor","extended.room":"kitchen"}`, []string{"temp"}},
+		{`{"field":"extended.level","value":null}`, []string{"light"}},
+		{`{"field":"extended.level","op":"gt","value":2}`, []string{"hygro"}},
+		{`{"field":"name","op":"in","value":["light","temp","none"]}`, []string{"light", "temp"}},
+		{`{"or":[{"field":"name","op":"like","value":"h%"},{"field":"type","op":"eq","value":"actuator"}]}`, []string{"hygro", "light"}},
+	}
+	for _, test := range tests {
+		filter, err := ParseFilter(test.body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		array := (&models.NodeBeans{}).New()
+		if err := store.Find((&models.NodeBean{}).New(), array, filter); err != nil {
+			t.Fatal(err)
+		}
+		found := names(array)
+		sort.Strings(found)
+		if !reflect.DeepEqual(found, test.names) {
+			t.Errorf("%s: found %v, expected %v", test.body, found, test.names)
+		}
+	}
+}
@@ -23,6 +23,10 @@
 package engine
 
 import (
+	"context"
+	"strconv"
+	"time"
+
 	"github.com/yroffin/go-boot-sqllite/core/models"
 	"github.com/yroffin/go-boot-sqllite/core/winter"
 )
@@ -32,6 +36,16 @@ type GraphCrudBusiness struct {
 	*winter.Service
 	// Store with injection mecanism
 	Store IGraphStore `@autowired:"cayley-manager"`
+	// Events with injection mecanism
+	Events winter.IEventBus `@autowired:"events"`
+	// MaxDepth of traversals
+	MaxDepth int `@value:"graph.traversal.depth" @default:"8"`
+	// MaxSize count of links of a traversal
+	MaxSize int `@value:"graph.traversal.size" @default:"10000"`
+	// QueryTimeout of queries
+	QueryTimeout time.Duration `@value:"graph.query.timeout" @default:"5s"`
+	// QueryLimit count of results of a query
+	QueryLimit int `@value:"graph.query.limit" @default:"1000"`
 }
 
 // New constructor
@@ -78,23 +92,41 @@ func (p *GraphCrudBusiness) Validate(name string) error {
 
 // CreateLink retrieve this link
 func (p *GraphCrudBusiness) CreateLink(toCreate models.IEdgeBean) (models.IEdgeBean, error) {
-	return toCreate, p.Store.CreateLink(toCreate)
+	err := p.Store.CreateLink(toCreate)
+	if err == nil {
+		p.Events.Publish(LinkCreated{Link: toCreate})
+	}
+	return toCreate, err
 }
 
 // UpdateLink retrieve this link
 func (p *GraphCrudBusiness) UpdateLink(toUpdate models.IEdgeBean) (models.IEdgeBean, error) {
-	return toUpdate, p.Store.UpdateLink(toUpdate)
+	err := p.Store.UpdateLink(toUpdate)
+	if err == nil {
+		p.Events.Publish(LinkUpdated{Link: toUpdate})
+	}
+	return toUpdate, err
 }
 
 // GetAllLink retrieve this bean by its id
 func (p *GraphCrudBusiness) GetAllLink(model string, id string, toGets []models.IEdgeBean, targetType string) ([]models.IEdgeBean, error) {
-	p.Store.GetAllLink(model, id, &toGets, targetType)
-	return toGets, nil
+	err := p.Store.GetAllLink(model, id, &toGets, targetType)
+	return toGets, err
+}
+
+// GetAllLinkTo retrieve all links targeting this bean
+func (p *GraphCrudBusiness) GetAllLinkTo(model string, id string, toGets []models.IEdgeBean) ([]models.IEdgeBean, error) {
+	err := p.Store.GetAllLinkTo(model, id, &toGets)
+	return toGets, err
 }
 
 // DeleteLink a bean
 func (p *GraphCrudBusiness) DeleteLink(toDelete models.IEdgeBean) (models.IEdgeBean, error) {
-	return toDelete, p.Store.DeleteLink(toDelete)
+	err := p.Store.DeleteLink(toDelete)
+	if err == nil {
+		p.Events.Publish(LinkDeleted{Link: toDelete})
+	}
+	return toDelete, err
 }
 
 // TruncateLink a bean
@@ -105,5 +137,103 @@ func (p *GraphCrudBusiness) TruncateLink(toTruncate models.IPersistent) (models.
 
 // PatchLink a bean
 func (p *GraphCrudBusiness) PatchLink(toPatch models.IEdgeBean) (models.IEdgeBean, error) {
-	return toPatch, p.Store.CreateLink(toPatch)
+	return p.CreateLink(toPatch)
+}
+
+// Transaction run fn with links undone if it fails, events are published
+// once fn succeeded
+func (p *GraphCrudBusiness) Transaction(fn func(ILinkBusiness) error) error {
+	events := &deferred{IEventBus: p.Events}
+	err := p.Store.Transaction(func(store IGraphStore) error {
+		bound := *p
+		bound.Store = store
+		bound.Events = events
+		return fn(&bound)
+	})
+	if err == nil {
+		events.flush()
+	}
+	return err
+}
+
+// Traverse follow links from the root of this traversal, breadth first with
+// a single gizmo query per depth
+func (p *GraphCrudBusiness) Traverse(traversal *Traversal) (*Branch, error) {
+	depth := traversal.depth()
+	if depth > p.MaxDepth {
+		return nil, &Error{Kind: Validation, Entity: traversal.From, Message: "depth " + strconv.Itoa(depth) + " exceeds " + strconv.Itoa(p.MaxDepth)}
+	}
+	root := &Branch{Entity: traversal.From, ID: traversal.ID}
+	visited := map[string]bool{root.node(): true}
+	frontier := []*Branch{root}
+	size := 0
+	for level := 0; level < depth && len(frontier) > 0; level++ {
+		nodes := make([]string, 0, len(frontier))
+		for _, branch := range frontier {
+			nodes = append(nodes, branch.node())
+		}
+		edges, err := p.Store.Out(nodes)
+		if err != nil {
+			return nil, internal(traversal.From, "link failure", err)
+		}
+		// links by source, once per instance
+		sources := make(map[string][]models.IEdgeBean)
+		instances := make(map[string]bool)
+		for _, edge := range edges {
+			if instances[edge.GetInstance()] {
+				continue
+			}
+			instances[edge.GetInstance()] = true
+			source := "/" + edge.GetSource() + "/" + edge.GetSourceID()
+			sources[source] = append(sources[source], edge)
+		}
+		hop := traversal.hop(level)
+		next := make([]*Branch, 0)
+		for _, branch := range frontier {
+			for _, edge := range sources[branch.node()] {
+				if !hop.accept(edge) {
+					continue
+				}
+				if size++; size > p.MaxSize {
+					return nil, &Error{Kind: Validation, Entity: traversal.From, Message: "traversal exceeds " + strconv.Itoa(p.MaxSize) + " links"}
+				}
+				child := &Branch{Entity: edge.GetTarget(), ID: edge.GetTargetID(), Edge: edge, parent: branch}
+				if traversal.Visit == VisitGlobal {
+					child.Visited = visited[child.node()]
+					visited[child.node()] = true
+				} else {
+					child.Visited = branch.onPath(child.node())
+				}
+				branch.Children = append(branch.Children, child)
+				if !child.Visited {
+					next = append(next, child)
+				}
+			}
+		}
+		frontier = next
+	}
+	return root, nil
+}
+
+// Query run a read only gizmo query, its results are truncated to its limit
+// and a query still running after the query timeout fails
+func (p *GraphCrudBusiness) Query(ctx context.Context, query *GraphQuery) (*GraphResult, error) {
+	limit := query.Limit
+	if limit == 0 || limit > p.QueryLimit {
+		limit = p.QueryLimit
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.QueryTimeout)
+	defer cancel()
+	results, err := p.Store.Query(ctx, query.Gizmo, limit+1)
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, &Error{Kind: Timeout, Message: "query exceeds " + p.QueryTimeout.String()}
+	}
+	if err != nil {
+		return nil, invalid("", "query failure", err)
+	}
+	output := &GraphResult{Results: results}
+	if len(results) > limit {
+		output.Results, output.Truncated = results[:limit], true
+	}
+	return output, nil
 }
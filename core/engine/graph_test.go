@@ -0,0 +1,107 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// queryStore a graph store answering count results, or failing, or
+// blocking until its context is done
+type queryStore struct {
+	IGraphStore
+	count int
+	err   error
+	block bool
+}
+
+// Query answer at most limit results
+func (p *queryStore) Query(ctx context.Context, text string, limit int) ([]map[string]interface{}, error) {
+	if p.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	results := make([]map[string]interface{}, 0)
+	for index := 0; index < p.count && index < limit; index++ {
+		results = append(results, map[string]interface{}{"value": index})
+	}
+	return results, p.err
+}
+
+func TestGraphQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		store     *queryStore
+		limit     int
+		results   int
+		truncated bool
+		err       bool
+		kind      Kind
+	}{
+		{"all", &queryStore{count: 3}, 0, 3, false, false, 0},
+		{"limit", &queryStore{count: 3}, 2, 2, true, false, 0},
+		{"bounded limit", &queryStore{count: 20}, 50, 10, true, false, 0},
+		{"failure", &queryStore{err: errors.New("syntax")}, 0, 0, false, true, Validation},
+		{"deadline", &queryStore{block: true}, 0, 0, false, true, Timeout},
+	}
+	for _, test := range tests {
+		graph := &GraphCrudBusiness{Store: test.store, QueryTimeout: 20 * time.Millisecond, QueryLimit: 10}
+		result, err := graph.Query(context.Background(), &GraphQuery{Gizmo: "g.V().All()", Limit: test.limit})
+		if test.err {
+			if err == nil || KindOf(err) != test.kind {
+				t.Errorf("%s: got %v", test.name, err)
+			}
+			continue
+		}
+		if err != nil || len(result.Results) != test.results || result.Truncated != test.truncated {
+			t.Errorf("%s: got %+v %v", test.name, result, err)
+		}
+	}
+}
+
+func TestTimeoutProblem(t *testing.T) {
+	problem := NewProblem("Graph", &Error{Kind: Timeout, Message: "query exceeds 5s"})
+	if problem.Status != http.StatusGatewayTimeout || problem.Type != TimeoutType || problem.Title != "Gateway Timeout" {
+		t.Errorf("got %+v", problem)
+	}
+	if problem := NewProblem("Graph", invalid("", "query failure", nil)); problem.Type != "about:blank" {
+		t.Errorf("got %+v", problem)
+	}
+}
+
+func TestGraphQueryStuck(t *testing.T) {
+	// a script which never yields answers with the deadline all the same
+	graph := &GraphCrudBusiness{Store: memoryGraph(t), QueryTimeout: 50 * time.Millisecond, QueryLimit: 10}
+	start := time.Now()
+	_, err := graph.Query(context.Background(), &GraphQuery{Gizmo: "while(true){}"})
+	if KindOf(err) != Timeout || NewProblem("Graph", err).Status != http.StatusGatewayTimeout {
+		t.Errorf("got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("answered after %v", elapsed)
+	}
+}
@@ -0,0 +1,40 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+// GraphQuery a read only gizmo query of the graph, see ILinkBusiness.Query
+//
+//	{"gizmo": "g.V('/NodeBean/1').Tag('source').Out().Tag('link').All()", "limit": 100}
+type GraphQuery struct {
+	// Gizmo javascript of this query
+	Gizmo string `json:"gizmo" @validate:"required"`
+	// Limit count of results, bounded by graph.query.limit
+	Limit int `json:"limit,omitempty" @validate:"min=0"`
+}
+
+// GraphResult results of a graph query, each maps its tags to their values
+type GraphResult struct {
+	Results []map[string]interface{} `json:"results"`
+	// Truncated true if the query has more results than its limit
+	Truncated bool `json:"truncated"`
+}
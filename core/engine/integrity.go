@@ -0,0 +1,177 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/yroffin/go-boot-sqllite/core/models"
+)
+
+const (
+	// Restrict deleting a source answers 409 while it has links
+	Restrict = "restrict"
+	// Unlink deleting a source deletes its links, the default
+	Unlink = "unlink"
+	// Cascade deleting a source deletes its links and their targets
+	Cascade = "cascade"
+)
+
+// Link a link declaration of an API
+//
+//	Link INode `@autowired:"NodeBean" @link:"/api/nodes" @href:"nodes" @ondelete:"cascade"`
+type Link struct {
+	// Href of this link
+	Href string
+	// Target API of this link
+	Target IAPI
+	// OnDelete Restrict, Unlink or Cascade
+	OnDelete string
+}
+
+// href of the link declaration targeting this API, edges are named after it
+func (p *API) href(target IAPI) string {
+	for _, link := range p.links {
+		if link.Target.GetName() == target.GetName() {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+// OnDelete check an @ondelete value, empty is Unlink
+func OnDelete(value string) (string, bool) {
+	switch value {
+	case "":
+		return Unlink, true
+	case Restrict, Unlink, Cascade:
+		return value, true
+	}
+	return Unlink, false
+}
+
+// cascadeKey key of the entities being deleted in a context
+type cascadeKey struct{}
+
+// deleting mark this entity as being deleted in ctx, false if it already
+// was: a cycle of cascaded links stops on it
+func deleting(ctx context.Context, entity models.IPersistent) (context.Context, bool) {
+	key := entity.GetEntityName() + "/" + entity.GetID()
+	marked, ok := ctx.Value(cascadeKey{}).(map[string]bool)
+	if !ok {
+		marked = make(map[string]bool)
+		ctx = context.WithValue(ctx, cascadeKey{}, marked)
+	}
+	if marked[key] {
+		return ctx, false
+	}
+	marked[key] = true
+	return ctx, true
+}
+
+// within a copy of this API writing in the unit of work of ctx, through
+// the same interceptors as its own beans
+func (p *API) within(ctx context.Context) *API {
+	unit, ok := UnitOf(ctx)
+	if !ok {
+		return p
+	}
+	bound := *p
+	bound.SQLCrudBusiness = boundCrud(p.SQLCrudBusiness, unit.Crud)
+	bound.GraphBusiness = boundLinks(p.GraphBusiness, unit.Links)
+	return &bound
+}
+
+// Cascade delete this entity in the unit of work of ctx, its links are
+// released first; an entity already gone or being deleted is ignored
+func (p *API) Cascade(ctx context.Context, id string) error {
+	api := p.within(ctx)
+	toDelete := p.Factory()
+	toDelete.SetID(id)
+	ctx, ok := deleting(ctx, toDelete)
+	if !ok {
+		return nil
+	}
+	if _, err := api.SQLCrudBusiness.Get(toDelete); err != nil {
+		if KindOf(err) == NotFound {
+			return nil
+		}
+		return err
+	}
+	if err := api.release(ctx, toDelete); err != nil {
+		return err
+	}
+	_, err := api.SQLCrudBusiness.Delete(toDelete)
+	return err
+}
+
+// released release the links of this entity in the unit of work of ctx,
+// see Operation
+func (p *API) released(ctx context.Context, entity models.IPersistent) error {
+	ctx, _ = deleting(ctx, entity)
+	return p.within(ctx).release(ctx, entity)
+}
+
+// release the links of this entity before its deletion, according to each
+// link declaration; links targeting it are deleted
+func (p *API) release(ctx context.Context, entity models.IPersistent) error {
+	if p.GraphBusiness == nil {
+		return nil
+	}
+	for _, link := range p.links {
+		edges, err := p.GraphBusiness.GetAllLink(p.entity(), entity.GetID(), make([]models.IEdgeBean, 0), link.Target.GetName())
+		if err != nil {
+			return internal(p.entity(), "link failure", err)
+		}
+		targets := make([]models.IEdgeBean, 0)
+		for _, edge := range edges {
+			if edge.GetTarget() == link.Target.GetFactory().GetEntityName() {
+				targets = append(targets, edge)
+			}
+		}
+		if len(targets) > 0 && link.OnDelete == Restrict {
+			return &Error{Kind: Conflict, Entity: p.entity(), Message: entity.GetID() + " still has " + strconv.Itoa(len(targets)) + " " + link.Href + " link(s)"}
+		}
+		for _, edge := range targets {
+			if _, err := p.GraphBusiness.DeleteLink(edge); err != nil {
+				return internal(p.entity(), "link failure", err)
+			}
+			if link.OnDelete == Cascade {
+				if err := link.Target.Cascade(ctx, edge.GetTargetID()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	edges, err := p.GraphBusiness.GetAllLinkTo(p.entity(), entity.GetID(), make([]models.IEdgeBean, 0))
+	if err != nil {
+		return internal(p.entity(), "link failure", err)
+	}
+	for _, edge := range edges {
+		if _, err := p.GraphBusiness.DeleteLink(edge); err != nil {
+			return internal(p.entity(), "link failure", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/yroffin/go-boot-sqllite/core/models"
+	"github.com/yroffin/go-boot-sqllite/core/winter"
+)
+
+func init() {
+	winter.Define("jobs", func() winter.IBean { return (&Jobs{}).New() })
+}
+
+const (
+	// JobsPath resource of jobs, see JobAPI
+	JobsPath = "/api/jobs"
+	// JobPending job waiting for a worker
+	JobPending = "pending"
+	// JobRunning job running
+	JobRunning = "running"
+	// JobDone job successfully done
+	JobDone = "done"
+	// JobFailed job failed
+	JobFailed = "failed"
+	// JobCanceled job canceled before its end
+	JobCanceled = "canceled"
+)
+
+// progressKey context key of the progress reporter of a job
+type progressKey struct{}
+
+// Progress report the progress of the job running with this context, it
+// does nothing outside jobs
+func Progress(ctx context.Context, percent int, message string) {
+	if report, ok := ctx.Value(progressKey{}).(func(int, string)); ok {
+		report(percent, message)
+	}
+}
+
+// IJobs job executor
+type IJobs interface {
+	winter.IService
+	// Submit queue this function as a job
+	Submit(task string, run func(ctx context.Context) (interface{}, error)) (*models.JobBean, error)
+	// Get a job
+	Get(id string) (*models.JobBean, error)
+	// Cancel a pending or running job
+	Cancel(id string) (*models.JobBean, error)
+}
+
+// work a queued job
+type work struct {
+	job    *models.JobBean
+	run    func(ctx context.Context) (interface{}, error)
+	ctx    context.Context
+	cancel context.CancelFunc
+	// progress last saved, and when
+	saved   int
+	savedAt time.Time
+}
+
+// Jobs run jobs on a bounded pool of workers, jobs are stored with their
+// progress, result and error
+type Jobs struct {
+	*winter.Service
+	// Store with injection mecanism
+	Store IDataStore `@autowired:""`
+	// Workers count
+	Workers int `@value:"jobs.workers" @default:"4"`
+	// Queue size, submits fail when it is full
+	Queue int `@value:"jobs.queue" @default:"100"`
+	// DrainTimeout wait for running jobs on shutdown
+	DrainTimeout time.Duration `@value:"jobs.drain" @default:"10s"`
+	// ProgressStep and ProgressInterval throttle progress saves, progress is
+	// saved once it moved by this step or after this interval, Get answers
+	// the latest progress of running jobs
+	ProgressStep     int           `@value:"jobs.progress.step" @default:"5"`
+	ProgressInterval time.Duration `@value:"jobs.progress.interval" @default:"1s"`
+	// queue of pending jobs
+	queue chan *work
+	// works by job id, until they end
+	works map[string]*work
+	// closed once destroyed
+	closed bool
+	mutex  sync.Mutex
+	wait   sync.WaitGroup
+}
+
+// New constructor
+func (p *Jobs) New() IJobs {
+	bean := Jobs{Service: &winter.Service{Bean: &winter.Bean{}}, works: make(map[string]*work)}
+	return &bean
+}
+
+// Init this bean
+func (p *Jobs) Init() error {
+	return nil
+}
+
+// PostConstruct fail jobs interrupted by a restart, then start workers
+func (p *Jobs) PostConstruct(name string) error {
+	jobs := (&models.JobBeans{}).New()
+	filter := &Filter{Field: "status", Op: "in", Value: []interface{}{JobPending, JobRunning}}
+	if err := p.Store.Find((&models.JobBean{}).New(), jobs, filter); err != nil {
+		return err
+	}
+	for _, bean := range jobs.Get() {
+		job := bean.(*models.JobBean)
+		p.finish(job, nil, &Error{Kind: Internal, Entity: job.GetEntityName(), Message: "interrupted by a restart"})
+	}
+	p.queue = make(chan *work, p.Queue)
+	for index := 0; index < p.Workers; index++ {
+		p.wait.Add(1)
+		go p.worker()
+	}
+	return nil
+}
+
+// Validate this bean
+func (p *Jobs) Validate(name string) error {
+	return nil
+}
+
+// PreDestroy cancel all jobs and wait for workers
+func (p *Jobs) PreDestroy(name string) error {
+	p.mutex.Lock()
+	if p.closed || p.queue == nil {
+		p.mutex.Unlock()
+		return nil
+	}
+	p.closed = true
+	for _, w := range p.works {
+		w.cancel()
+	}
+	close(p.queue)
+	p.mutex.Unlock()
+	done := make(chan struct{})
+	go func() {
+		p.wait.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-time.After(p.DrainTimeout):
+		return fmt.Errorf("jobs still running after %v", p.DrainTimeout)
+	}
+}
+
+// Submit queue this function as a job
+func (p *Jobs) Submit(task string, run func(ctx context.Context) (interface{}, error)) (*models.JobBean, error) {
+	job := &models.JobBean{Task: task, Status: JobPending, Created: models.JSONTime(time.Now())}
+	if err := p.Store.Create(job); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &work{job: job, run: run, ctx: ctx, cancel: cancel}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if !p.closed && p.queue != nil {
+		select {
+		case p.queue <- w:
+			p.works[job.ID] = w
+			return job.Copy().(*models.JobBean), nil
+		default:
+		}
+	}
+	cancel()
+	p.Store.Delete(job.ID, job)
+	return nil, &Error{Kind: Unavailable, Entity: job.GetEntityName(), Message: "job queue is full"}
+}
+
+// worker run queued jobs
+func (p *Jobs) worker() {
+	defer p.wait.Done()
+	for w := range p.queue {
+		p.execute(w)
+	}
+}
+
+// execute a queued job, unless canceled
+func (p *Jobs) execute(w *work) {
+	defer w.cancel()
+	p.mutex.Lock()
+	if w.job.Finished != nil {
+		// canceled while pending
+		p.mutex.Unlock()
+		return
+	}
+	if w.ctx.Err() != nil {
+		p.end(w.job, nil, w.ctx.Err())
+		p.mutex.Unlock()
+		return
+	}
+	w.job.Status = JobRunning
+	p.save(w.job)
+	w.saved, w.savedAt = w.job.Progress, time.Now()
+	p.mutex.Unlock()
+	ctx := context.WithValue(w.ctx, progressKey{}, func(percent int, message string) {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		w.job.Progress, w.job.Message = percent, message
+		p.progress(w)
+	})
+	result, err := p.safely(ctx, w.run)
+	if err != nil && w.ctx.Err() != nil {
+		err = w.ctx.Err()
+	}
+	p.finish(w.job, result, err)
+}
+
+// safely run a job, a panic fails it
+func (p *Jobs) safely(ctx context.Context, run func(ctx context.Context) (interface{}, error)) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = internal("JobBean", "job panic", fmt.Errorf("%v", r))
+		}
+	}()
+	return run(ctx)
+}
+
+// finish a job with its result or error, context errors cancel it
+func (p *Jobs) finish(job *models.JobBean, result interface{}, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.end(job, result, err)
+}
+
+// end a job, while locked
+func (p *Jobs) end(job *models.JobBean, result interface{}, err error) {
+	finished := models.JSONTime(time.Now())
+	job.Finished = &finished
+	switch {
+	case err == context.Canceled || err == context.DeadlineExceeded:
+		job.Status = JobCanceled
+	case err != nil:
+		problem := NewProblem(job.GetEntityName(), err)
+		job.Status, job.Error = JobFailed, &problem
+	default:
+		job.Status, job.Result, job.Progress = JobDone, result, 100
+	}
+	p.save(job)
+	delete(p.works, job.ID)
+	log.WithFields(log.Fields{
+		"id":     job.ID,
+		"task":   job.Task,
+		"status": job.Status,
+		"error":  err,
+	}).Info("Job")
+}
+
+// progress save the progress of a running job once it moved by a step or
+// after an interval, while locked
+func (p *Jobs) progress(w *work) {
+	moved := w.job.Progress - w.saved
+	if moved < 0 {
+		moved = -moved
+	}
+	if moved < p.ProgressStep && time.Since(w.savedAt) < p.ProgressInterval {
+		return
+	}
+	p.save(w.job)
+	w.saved, w.savedAt = w.job.Progress, time.Now()
+}
+
+// save a job, while locked
+func (p *Jobs) save(job *models.JobBean) {
+	if err := p.Store.Update(job.ID, job); err != nil {
+		log.WithFields(log.Fields{
+			"id":    job.ID,
+			"error": err,
+		}).Error("Job")
+	}
+}
+
+// Get a job, running jobs answer their latest progress
+func (p *Jobs) Get(id string) (*models.JobBean, error) {
+	p.mutex.Lock()
+	if w, ok := p.works[id]; ok {
+		job := w.job.Copy().(*models.JobBean)
+		p.mutex.Unlock()
+		return job, nil
+	}
+	p.mutex.Unlock()
+	job := &models.JobBean{}
+	if err := p.Store.Get(id, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Cancel a pending or running job, pending jobs are canceled at once and
+// running ones once their task returns
+func (p *Jobs) Cancel(id string) (*models.JobBean, error) {
+	p.mutex.Lock()
+	w, ok := p.works[id]
+	p.mutex.Unlock()
+	if !ok {
+		job, err := p.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, conflict(job.GetEntityName(), "job "+id+" is "+job.Status, nil)
+	}
+	w.cancel()
+	p.mutex.Lock()
+	if w.job.Status == JobPending {
+		p.end(w.job, nil, context.Canceled)
+	}
+	p.mutex.Unlock()
+	return p.Get(id)
+}
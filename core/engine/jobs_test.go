@@ -0,0 +1,96 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yroffin/go-boot-sqllite/core/models"
+)
+
+// countingStore a store counting its updates
+type countingStore struct {
+	*Store
+	updates int
+}
+
+// Update count this update
+func (p *countingStore) Update(id string, entity models.IPersistent) error {
+	p.updates++
+	return p.Store.Update(id, entity)
+}
+
+func TestJobProgress(t *testing.T) {
+	store := memoryStore(t)
+	defer store.PreDestroy("store")
+	if err := store.table("JobBean"); err != nil {
+		t.Fatal(err)
+	}
+	jobs := (&Jobs{}).New().(*Jobs)
+	counting := &countingStore{Store: store}
+	jobs.Store, jobs.Workers, jobs.Queue = counting, 1, 1
+	jobs.DrainTimeout, jobs.ProgressStep, jobs.ProgressInterval = time.Second, 10, time.Hour
+	if err := jobs.PostConstruct("jobs"); err != nil {
+		t.Fatal(err)
+	}
+	halfway := make(chan struct{})
+	resume := make(chan struct{})
+	job, err := jobs.Submit("count", func(ctx context.Context) (interface{}, error) {
+		for index := 0; index < 1000; index++ {
+			Progress(ctx, index/10, "counting")
+			if index == 500 {
+				close(halfway)
+				<-resume
+			}
+		}
+		return "counted", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-halfway
+	running, err := jobs.Get(job.ID)
+	if err != nil || running.Status != JobRunning || running.Progress != 50 {
+		t.Errorf("running job %+v %v", running, err)
+	}
+	stored := &models.JobBean{}
+	if err := store.Get(job.ID, stored); err != nil || stored.Progress != 50 {
+		t.Errorf("stored job %+v %v", stored, err)
+	}
+	close(resume)
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if err := store.Get(job.ID, stored); err == nil && stored.Finished != nil {
+			break
+		}
+	}
+	if stored.Status != JobDone || stored.Progress != 100 {
+		t.Fatalf("done job %+v", stored)
+	}
+	jobs.PreDestroy("jobs")
+	// running, every 10 percent but 0 and done
+	if counting.updates != 11 {
+		t.Errorf("job saved %d times", counting.updates)
+	}
+}
@@ -0,0 +1,105 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"github.com/yroffin/go-boot-sqllite/core/models"
+	"github.com/yroffin/go-boot-sqllite/core/winter"
+)
+
+func init() {
+	winter.Define("JobAPI", func() winter.IBean { return (&JobAPI{}).New() })
+}
+
+// JobAPI poll and cancel jobs of asynchronous tasks
+type JobAPI struct {
+	// Base component
+	*API
+	// mounts
+	Poll   interface{} `@handler:"HandlerJobGetByID" path:"/api/jobs/:id" method:"GET"`
+	Cancel interface{} `@handler:"HandlerJobDeleteByID" path:"/api/jobs/:id" method:"DELETE"`
+	// Swagger with injection mecanism
+	Swagger ISwaggerService `@autowired:"swagger"`
+}
+
+// IJobAPI implements IBean
+type IJobAPI interface {
+	IAPI
+}
+
+// New constructor
+func (p *JobAPI) New() IJobAPI {
+	bean := &JobAPI{API: &API{Bean: &winter.Bean{}}}
+	return bean
+}
+
+// Init this API, its factory declares the jobs table
+func (p *JobAPI) Init() error {
+	p.Factory = func() models.IPersistent {
+		return (&models.JobBean{}).New()
+	}
+	p.Factories = func() models.IPersistents {
+		return (&models.JobBeans{}).New()
+	}
+	return p.API.Init()
+}
+
+// PostConstruct this API
+func (p *JobAPI) PostConstruct(name string) error {
+	// Scan struct and init all handler
+	p.ScanHandler(p.Swagger, p)
+	return nil
+}
+
+// Validate this API
+func (p *JobAPI) Validate(name string) error {
+	return nil
+}
+
+// HandlerJobGetByID is the GET by ID handler of jobs
+func (p *JobAPI) HandlerJobGetByID() func(c IHttpContext) {
+	anonymous := func(c IHttpContext) {
+		c.Header("Content-type", "application/json")
+		job, err := p.Jobs.Get(c.Param("id"))
+		if err != nil {
+			p.Problem(c, err)
+			return
+		}
+		c.IndentedJSON(200, job)
+	}
+	return anonymous
+}
+
+// HandlerJobDeleteByID cancel a pending or running job, 409 once ended
+func (p *JobAPI) HandlerJobDeleteByID() func(c IHttpContext) {
+	anonymous := func(c IHttpContext) {
+		c.Header("Content-type", "application/json")
+		job, err := p.Jobs.Cancel(c.Param("id"))
+		if err != nil {
+			p.Problem(c, err)
+			return
+		}
+		c.IndentedJSON(202, job)
+	}
+	return anonymous
+}
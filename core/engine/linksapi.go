@@ -0,0 +1,250 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"github.com/yroffin/go-boot-sqllite/core/models"
+	"github.com/yroffin/go-boot-sqllite/core/winter"
+)
+
+func init() {
+	winter.Define("LinkAPI", func() winter.IBean { return (&LinkAPI{}).New() })
+}
+
+var (
+	// ErrQueryDisabled returned by the query handler without token
+	ErrQueryDisabled = &Error{Kind: NotFound, Message: "graph queries are disabled (graph.query.token)"}
+)
+
+// LinkAPI administration of links, POST /api/links?task=repair finds and
+// deletes dangling links, POST /api/links/query runs read only queries
+type LinkAPI struct {
+	// Base component
+	*API
+	// mounts
+	Tasks interface{} `@handler:"HandlerLinkTask" path:"/api/links" method:"POST"`
+	Gizmo interface{} `@handler:"HandlerLinkQuery" path:"/api/links/query" method:"POST"`
+	// Token bearer token of queries, queries are disabled without it
+	Token string `@value:"graph.query.token"`
+	// tasks
+	Repair func(ctx context.Context, input *RepairInput) (*RepairOutput, error) `@task:"repair" @async:"true"`
+	// All APIs with injection mecanism (by type)
+	APIs []IAPI `@autowired:""`
+	// Swagger with injection mecanism
+	Swagger ISwaggerService `@autowired:"swagger"`
+}
+
+// ILinkAPI implements IBean
+type ILinkAPI interface {
+	IAPI
+}
+
+// RepairInput input of the repair task
+type RepairInput struct {
+	// DryRun only finds dangling links
+	DryRun bool `json:"dryRun"`
+}
+
+// RepairOutput output of the repair task
+type RepairOutput struct {
+	Dangling []Dangling `json:"dangling"`
+	Deleted  int        `json:"deleted"`
+}
+
+// Dangling a link whose source or target entity is gone
+type Dangling struct {
+	Instance string `json:"instance"`
+	Link     string `json:"link"`
+	Source   string `json:"source"`
+	SourceID string `json:"sourceId"`
+	Target   string `json:"target"`
+	TargetID string `json:"targetId"`
+	// Missing source or target
+	Missing string `json:"missing"`
+}
+
+// New constructor
+func (p *LinkAPI) New() ILinkAPI {
+	bean := &LinkAPI{API: &API{Bean: &winter.Bean{}}}
+	return bean
+}
+
+// Init this API
+func (p *LinkAPI) Init() error {
+	p.Repair = p.repair
+	return p.API.Init()
+}
+
+// PostConstruct this API
+func (p *LinkAPI) PostConstruct(name string) error {
+	// Scan struct and init all handler
+	p.ScanHandler(p.Swagger, p)
+	return nil
+}
+
+// Validate this API
+func (p *LinkAPI) Validate(name string) error {
+	return nil
+}
+
+// HandlerLinkTask run a task on links
+func (p *LinkAPI) HandlerLinkTask() func(c IHttpContext) {
+	anonymous := func(c IHttpContext) {
+		c.Header("Content-type", "application/json")
+		body, _ := c.GetRawData()
+		task, ok := p.Task(c.Query("task"), false)
+		if !ok {
+			p.Problem(c, unknownTask(p.entity(), c.Query("task")))
+			return
+		}
+		p.HandlerTask(c, task, "", body)
+	}
+	return anonymous
+}
+
+// HandlerLinkQuery run a read only gizmo query of the graph, clients must
+// send the bearer token of graph.query.token
+func (p *LinkAPI) HandlerLinkQuery() func(c IHttpContext) {
+	anonymous := func(c IHttpContext) {
+		c.Header("Content-type", "application/json")
+		if err := p.authorize(c.GetHeader("Authorization")); err != nil {
+			if KindOf(err) == Unauthorized {
+				c.Header("WWW-Authenticate", "Bearer")
+			}
+			p.Problem(c, err)
+			return
+		}
+		body, _ := c.GetRawData()
+		query := &GraphQuery{}
+		if err := p.unmarshal(string(body), query); err != nil {
+			p.Problem(c, err)
+			return
+		}
+		if err := ValidateValue(p.entity(), query); err != nil {
+			p.Problem(c, err)
+			return
+		}
+		if p.GraphBusiness == nil {
+			p.Problem(c, ErrLinksDisabled)
+			return
+		}
+		result, err := p.GraphBusiness.Query(p.Context(c), query)
+		if err != nil {
+			p.Problem(c, err)
+			return
+		}
+		c.IndentedJSON(200, result)
+	}
+	return anonymous
+}
+
+// authorize this Authorization header against the query token
+func (p *LinkAPI) authorize(header string) error {
+	if len(p.Token) == 0 {
+		return ErrQueryDisabled
+	}
+	token := strings.TrimPrefix(header, "Bearer ")
+	if token == header || subtle.ConstantTimeCompare([]byte(token), []byte(p.Token)) != 1 {
+		return &Error{Kind: Unauthorized, Message: "missing or wrong bearer token"}
+	}
+	return nil
+}
+
+// repair find links whose source or target entity is gone, and delete them
+// unless in dry run; links of entities without API are kept
+func (p *LinkAPI) repair(ctx context.Context, input *RepairInput) (*RepairOutput, error) {
+	if p.GraphBusiness == nil {
+		return nil, ErrLinksDisabled
+	}
+	quads, err := p.GraphBusiness.All()
+	if err != nil {
+		return nil, internal(p.entity(), "link failure", err)
+	}
+	apis := make(map[string]IAPI)
+	for _, api := range p.APIs {
+		if bean := api.GetFactory(); bean != nil {
+			apis[bean.GetEntityName()] = api
+		}
+	}
+	output := &RepairOutput{Dangling: make([]Dangling, 0)}
+	known := make(map[string]bool)
+	for index, quad := range quads {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		Progress(ctx, index*100/len(quads), "checking links")
+		dangling := Dangling{Instance: quad.PredicateID(), Link: quad.Predicate(), Source: quad.Subject(), SourceID: quad.SubjectID(), Target: quad.Object(), TargetID: quad.ObjectID()}
+		source, err := p.exists(apis[quad.Subject()], quad.SubjectID(), known)
+		if err != nil {
+			return nil, err
+		}
+		target, err := p.exists(apis[quad.Object()], quad.ObjectID(), known)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case !source:
+			dangling.Missing = "source"
+		case !target:
+			dangling.Missing = "target"
+		default:
+			continue
+		}
+		output.Dangling = append(output.Dangling, dangling)
+	}
+	if input.DryRun {
+		return output, nil
+	}
+	for _, dangling := range output.Dangling {
+		edge := (&models.EdgeBean{}).New(dangling.Source, dangling.SourceID, dangling.Target, dangling.TargetID, dangling.Link)
+		edge.SetInstance(dangling.Instance)
+		if _, err := p.GraphBusiness.DeleteLink(edge); err != nil {
+			return output, internal(p.entity(), "link failure", err)
+		}
+		output.Deleted++
+	}
+	return output, nil
+}
+
+// exists true if this entity is stored, or handled by no API
+func (p *LinkAPI) exists(api IAPI, id string, known map[string]bool) (bool, error) {
+	if api == nil {
+		return true, nil
+	}
+	bean := api.GetFactory()
+	bean.SetID(id)
+	key := bean.GetEntityName() + "/" + id
+	if exists, ok := known[key]; ok {
+		return exists, nil
+	}
+	_, err := p.SQLCrudBusiness.Get(bean)
+	if err != nil && KindOf(err) != NotFound {
+		return false, err
+	}
+	known[key] = err == nil
+	return err == nil, nil
+}
@@ -32,18 +32,23 @@ import (
 )
 
 func init() {
-	winter.Helper.Register("APIManager", (&APIManager{}).New())
+	winter.Define("APIManager", func() winter.IBean { return (&APIManager{}).New() })
+	// flags are read by winter configuration once parsed
+	flag.Int("http", -1, "Http port")
+	flag.Int("https", -1, "Https port")
+	flag.String("certFile", "", "cert file")
+	flag.String("keyFile", "", "key file")
 }
 
 // APIManager interface
 type APIManager struct {
 	*winter.Service
 	// Properties
-	phttp *int
+	HTTPPort int `@value:"http" @default:"-1"`
 	// Properties
-	phttps   *int
-	certFile *string
-	keyFile  *string
+	HTTPSPort int    `@value:"https" @default:"-1"`
+	CertFile  string `@value:"certFile"`
+	KeyFile   string `@value:"keyFile"`
 	// Inject
 	Router IRouter `@autowired:"router"`
 }
@@ -66,32 +71,33 @@ func (m *APIManager) Init() error {
 	return nil
 }
 
-// CommandLine Init
+// CommandLine parse flags once
 func (m *APIManager) CommandLine() error {
-	// scan flags
-	m.phttp = flag.Int("http", -1, "Http port")
-	m.phttps = flag.Int("https", -1, "Https port")
-	m.certFile = flag.String("certFile", "", "cert file")
-	m.keyFile = flag.String("keyFile", "", "key file")
-	flag.Parse()
+	if !flag.Parsed() {
+		flag.Parse()
+	}
 	return nil
 }
 
 // Validate Init this manager
 func (m *APIManager) Validate(name string) error {
-	if *m.phttp != -1 {
+	if m.HTTPPort != -1 {
 		// Declarre listener HTTP
 		log.WithFields(log.Fields{
-			"port": *m.phttp,
+			"port": m.HTTPPort,
 		}).Info("Boot listener http")
-		go m.Router.HTTP(*m.phttp)
+		if err := m.Router.HTTP(m.HTTPPort); err != nil {
+			return fmt.Errorf("unable to listen on http port %d: %v", m.HTTPPort, err)
+		}
 	}
-	if *m.phttps != -1 {
+	if m.HTTPSPort != -1 {
 		// Declarre listener HTTPS
 		log.WithFields(log.Fields{
-			"port": *m.phttps,
+			"port": m.HTTPSPort,
 		}).Info("Boot listener https")
-		go m.Router.HTTPS(*m.phttps, *m.certFile, *m.keyFile)
+		if err := m.Router.HTTPS(m.HTTPSPort, m.CertFile, m.KeyFile); err != nil {
+			return fmt.Errorf("unable to listen on https port %d: %v", m.HTTPSPort, err)
+		}
 	}
 	return nil
 }
@@ -28,7 +28,7 @@ import (
 )
 
 func init() {
-	winter.Helper.Register("NodeBean", (&Node{}).New())
+	winter.Define("NodeBean", func() winter.IBean { return (&Node{}).New() })
 }
 
 // Node internal members
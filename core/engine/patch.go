@@ -0,0 +1,324 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MergePatch RFC 7396 content type, also used for application/json
+	MergePatch = "application/merge-patch+json"
+	// JSONPatch RFC 6902 content type
+	JSONPatch = "application/json-patch+json"
+)
+
+// Patcher apply a patch to a stored json document
+type Patcher func(document []byte) ([]byte, error)
+
+// operation a RFC 6902 operation
+type operation struct {
+	Op    string           `json:"op"`
+	Path  *string          `json:"path"`
+	From  *string          `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// NewPatcher decode a patch body according to its content type, json
+// bodies are merge patches and any other type is unsupported
+func NewPatcher(contentType string, body []byte) (Patcher, error) {
+	switch contentType {
+	case MergePatch, "application/json":
+	case JSONPatch:
+		operations := make([]operation, 0)
+		if err := json.Unmarshal(body, &operations); err != nil {
+			return nil, invalid("", "malformed json patch", err)
+		}
+		return func(document []byte) ([]byte, error) {
+			return applyOperations(document, operations)
+		}, nil
+	default:
+		return nil, unsupported("", contentType)
+	}
+	var patch interface{}
+	if err := json.Unmarshal(body, &patch); err != nil {
+		return nil, invalid("", "malformed merge patch", err)
+	}
+	return func(document []byte) ([]byte, error) {
+		var target interface{}
+		if err := json.Unmarshal(document, &target); err != nil {
+			return nil, internal("", "stored document is corrupted", err)
+		}
+		return json.Marshal(mergePatch(target, patch))
+	}, nil
+}
+
+// mergePatch RFC 7396, null values remove members
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	members, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	result, ok := target.(map[string]interface{})
+	if !ok {
+		result = make(map[string]interface{})
+	}
+	for key, value := range members {
+		if value == nil {
+			delete(result, key)
+			continue
+		}
+		result[key] = mergePatch(result[key], value)
+	}
+	return result
+}
+
+// applyOperations RFC 6902, all operations succeed or none is applied
+func applyOperations(document []byte, operations []operation) ([]byte, error) {
+	var root interface{}
+	if err := json.Unmarshal(document, &root); err != nil {
+		return nil, internal("", "stored document is corrupted", err)
+	}
+	for index, op := range operations {
+		var err error
+		if root, err = op.apply(root); err != nil {
+			var typed *Error
+			if errors.As(err, &typed) {
+				typed.Message = "operation " + strconv.Itoa(index) + ": " + typed.Message
+			}
+			return nil, err
+		}
+	}
+	return json.Marshal(root)
+}
+
+// apply this operation to the document root
+func (o *operation) apply(root interface{}) (interface{}, error) {
+	if o.Path == nil {
+		return nil, invalid("", o.Op+" needs a path", nil)
+	}
+	path, err := pointer(*o.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch o.Op {
+	case "add", "replace", "test":
+		if o.Value == nil {
+			return nil, invalid("", o.Op+" needs a value", nil)
+		}
+		var value interface{}
+		if err := json.Unmarshal(*o.Value, &value); err != nil {
+			return nil, invalid("", "malformed value", err)
+		}
+		switch o.Op {
+		case "add":
+			return pointerAdd(root, path, value)
+		case "replace":
+			if len(path) == 0 {
+				return value, nil
+			}
+			if _, err := pointerGet(root, path); err != nil {
+				return nil, err
+			}
+			if root, err = pointerRemove(root, path); err != nil {
+				return nil, err
+			}
+			return pointerAdd(root, path, value)
+		}
+		current, err := pointerGet(root, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, conflict("", "test failed on "+*o.Path, nil)
+		}
+		return root, nil
+	case "remove":
+		return pointerRemove(root, path)
+	case "move", "copy":
+		if o.From == nil {
+			return nil, invalid("", o.Op+" needs a from", nil)
+		}
+		from, err := pointer(*o.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := pointerGet(root, from)
+		if err != nil {
+			return nil, err
+		}
+		if o.Op == "move" {
+			if strings.HasPrefix(*o.Path+"/", *o.From+"/") && *o.Path != *o.From {
+				return nil, invalid("", "can not move "+*o.From+" into itself", nil)
+			}
+			if root, err = pointerRemove(root, from); err != nil {
+				return nil, err
+			}
+		} else {
+			// copies must not share maps or slices
+			data, _ := json.Marshal(value)
+			json.Unmarshal(data, &value)
+		}
+		return pointerAdd(root, path, value)
+	}
+	return nil, invalid("", "unknown operation "+o.Op, nil)
+}
+
+// pointer split a RFC 6901 json pointer
+func pointer(text string) ([]string, error) {
+	if len(text) == 0 {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(text, "/") {
+		return nil, invalid("", "path "+text+" must start with /", nil)
+	}
+	tokens := strings.Split(text[1:], "/")
+	for index, token := range tokens {
+		tokens[index] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+// arrayIndex of an array token, end allows - and len for insertion
+func arrayIndex(array []interface{}, token string, end bool) (int, error) {
+	if end && token == "-" {
+		return len(array), nil
+	}
+	position, err := strconv.Atoi(token)
+	if err != nil || position < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, invalid("", "invalid array index "+token, nil)
+	}
+	var max = len(array) - 1
+	if end {
+		max = len(array)
+	}
+	if position > max {
+		return 0, invalid("", "array index "+token+" is out of bounds", nil)
+	}
+	return position, nil
+}
+
+// pointerGet the value at this path
+func pointerGet(node interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch container := node.(type) {
+		case map[string]interface{}:
+			value, ok := container[token]
+			if !ok {
+				return nil, invalid("", "no member "+token, nil)
+			}
+			node = value
+		case []interface{}:
+			position, err := arrayIndex(container, token, false)
+			if err != nil {
+				return nil, err
+			}
+			node = container[position]
+		default:
+			return nil, invalid("", "no member "+token, nil)
+		}
+	}
+	return node, nil
+}
+
+// pointerAdd a value at this path, the parent must exist
+func pointerAdd(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := pointerGet(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	var token = path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[token] = value
+		return root, nil
+	case []interface{}:
+		position, err := arrayIndex(container, token, true)
+		if err != nil {
+			return nil, err
+		}
+		container = append(container, nil)
+		copy(container[position+1:], container[position:])
+		container[position] = value
+		return pointerSet(root, path[:len(path)-1], container)
+	}
+	return nil, invalid("", "can not add member "+token, nil)
+}
+
+// pointerRemove the value at this path
+func pointerRemove(root interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, invalid("", "can not remove the whole document", nil)
+	}
+	parent, err := pointerGet(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	var token = path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := container[token]; !ok {
+			return nil, invalid("", "no member "+token, nil)
+		}
+		delete(container, token)
+		return root, nil
+	case []interface{}:
+		position, err := arrayIndex(container, token, false)
+		if err != nil {
+			return nil, err
+		}
+		shrunk := append(container[:position:position], container[position+1:]...)
+		return pointerSet(root, path[:len(path)-1], shrunk)
+	}
+	return nil, invalid("", "no member "+token, nil)
+}
+
+// pointerSet replace the value at this existing path, slices may be reallocated
+func pointerSet(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := pointerGet(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	var token = path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[token] = value
+	case []interface{}:
+		position, err := arrayIndex(container, token, false)
+		if err != nil {
+			return nil, err
+		}
+		container[position] = value
+	}
+	return root, nil
+}
@@ -0,0 +1,181 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/yroffin/go-boot-sqllite/core/models"
+)
+
+// sameJSON compare two json documents
+func sameJSON(t *testing.T, got []byte, expected string) bool {
+	var left, right interface{}
+	if err := json.Unmarshal(got, &left); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(expected), &right); err != nil {
+		t.Fatal(err)
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+func TestMergePatch(t *testing.T) {
+	// RFC 7396 appendix A
+	tests := []struct {
+		document string
+		patch    string
+		result   string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, test := range tests {
+		patcher, err := NewPatcher(MergePatch, []byte(test.patch))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := patcher([]byte(test.document))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sameJSON(t, result, test.result) {
+			t.Errorf("%s + %s: got %s, expected %s", test.document, test.patch, result, test.result)
+		}
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		patch    string
+		result   string
+		// kind of the expected error, result is ignored then
+		err  bool
+		kind Kind
+	}{
+		{"add member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"foo":"bar","baz":"qux"}`, false, 0},
+		{"add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`, false, 0},
+		{"append", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc"]}]`, `{"foo":["bar",["abc"]]}`, false, 0},
+		{"remove member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`, false, 0},
+		{"remove element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`, false, 0},
+		{"replace", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`, false, 0},
+		{"move member", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`, false, 0},
+		{"move element", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`, false, 0},
+		{"copy", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`, false, 0},
+		{"test", `{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`, false, 0},
+		{"escaped pointer", `{"a/b":1,"m~n":2}`, `[{"op":"remove","path":"/a~1b"},{"op":"remove","path":"/m~0n"}]`, `{}`, false, 0},
+		{"whole document", `{"a":1}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`, false, 0},
+		{"test failure", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, ``, true, Conflict},
+		{"missing parent", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, ``, true, Validation},
+		{"missing member", `{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, ``, true, Validation},
+		{"out of bounds", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/2","value":1}]`, ``, true, Validation},
+		{"leading zero", `{"foo":["bar","baz"]}`, `[{"op":"remove","path":"/foo/01"}]`, ``, true, Validation},
+		{"move into itself", `{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a/c"}]`, ``, true, Validation},
+		{"unknown operation", `{}`, `[{"op":"drop","path":"/a"}]`, ``, true, Validation},
+		{"missing value", `{}`, `[{"op":"add","path":"/a"}]`, ``, true, Validation},
+		{"relative path", `{}`, `[{"op":"add","path":"a","value":1}]`, ``, true, Validation},
+	}
+	for _, test := range tests {
+		patcher, err := NewPatcher(JSONPatch, []byte(test.patch))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := patcher([]byte(test.document))
+		if test.err {
+			if err == nil || KindOf(err) != test.kind {
+				t.Errorf("%s: expected a %v error, got %v", test.name, test.kind, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !sameJSON(t, result, test.result) {
+			t.Errorf("%s: got %s, expected %s", test.name, result, test.result)
+		}
+	}
+}
+
+func TestMalformedPatch(t *testing.T) {
+	for _, contentType := range []string{MergePatch, JSONPatch} {
+		if _, err := NewPatcher(contentType, []byte(`{`)); KindOf(err) != Validation {
+			t.Errorf("%s: malformed patch answers %v", contentType, err)
+		}
+	}
+}
+
+func TestUnsupportedPatch(t *testing.T) {
+	for _, contentType := range []string{"", "text/plain", "application/xml"} {
+		_, err := NewPatcher(contentType, []byte(`{"name":"hot"}`))
+		if KindOf(err) != UnsupportedMediaType || NewProblem("Node", err).Status != http.StatusUnsupportedMediaType {
+			t.Errorf("%q: got %v", contentType, err)
+		}
+	}
+	if _, err := NewPatcher("application/json", []byte(`{"name":"hot"}`)); err != nil {
+		t.Errorf("application/json: got %v", err)
+	}
+}
+
+func TestStorePatch(t *testing.T) {
+	bean := node("temp", "sensor", map[string]interface{}{"room": "kitchen"})
+	store := memoryStore(t, bean)
+	defer store.PreDestroy("store")
+	patcher, _ := NewPatcher(MergePatch, []byte(`{"name":"hot","extended":{"room":null,"level":2}}`))
+	patched := (&models.NodeBean{}).New().(*models.NodeBean)
+	if err := store.Patch(bean.GetID(), patched, patcher); err != nil {
+		t.Fatal(err)
+	}
+	if patched.Name != "hot" || patched.Type != "sensor" || !reflect.DeepEqual(patched.Extended, map[string]interface{}{"level": 2.0}) || patched.Version != 2 {
+		t.Errorf("got %+v", patched)
+	}
+	// a failed patch leaves the document as is
+	failing, _ := NewPatcher(JSONPatch, []byte(`[{"op":"replace","path":"/name","value":"x"},{"op":"test","path":"/type","value":"actuator"}]`))
+	if err := store.Patch(bean.GetID(), (&models.NodeBean{}).New(), failing); KindOf(err) != Conflict {
+		t.Errorf("failed test answers %v", err)
+	}
+	stored := (&models.NodeBean{}).New().(*models.NodeBean)
+	if err := store.Get(bean.GetID(), stored); err != nil || stored.Name != "hot" || stored.Version != 2 {
+		t.Errorf("got %+v %v", stored, err)
+	}
+	if err := store.Patch("missing", (&models.NodeBean{}).New(), patcher); KindOf(err) != NotFound {
+		t.Errorf("missing id answers %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Order a sort key, Field uses the Filter field syntax
+type Order struct {
+	Field string
+	Desc  bool
+}
+
+// Query a filtered, sorted, paginated and projected selection
+type Query struct {
+	// Filter rows, nil match all
+	Filter *Filter
+	// Sort keys, rows are finally sorted by id
+	Sort []Order
+	// Fields projection, empty for whole entities
+	Fields []string
+	// Limit page size, 0 for no limit
+	Limit int
+	// Offset rows to skip
+	Offset int
+	// Cursor returned by a previous page, it replaces Offset
+	Cursor string
+}
+
+// Page describe a query result
+type Page struct {
+	// Total rows matching the filter
+	Total int
+	// Next cursor, empty on the last page
+	Next string
+}
+
+// ParseQuery read limit, offset, cursor, sort and fields query parameters
+//
+//	?limit=20&sort=type,-extended.level&fields=name,extended.room
+func ParseQuery(c IHttpContext) (*Query, error) {
+	query := &Query{Cursor: c.Query("cursor")}
+	var err error
+	if query.Limit, err = positive(c, "limit"); err != nil {
+		return nil, err
+	}
+	if query.Offset, err = positive(c, "offset"); err != nil {
+		return nil, err
+	}
+	for _, field := range split(c.Query("sort")) {
+		order := Order{Field: strings.TrimPrefix(field, "+")}
+		if strings.HasPrefix(field, "-") {
+			order = Order{Field: field[1:], Desc: true}
+		}
+		if !filterField.MatchString(order.Field) {
+			return nil, errors.New("sort field " + order.Field + " is invalid")
+		}
+		query.Sort = append(query.Sort, order)
+	}
+	for _, field := range split(c.Query("fields")) {
+		if !filterField.MatchString(field) {
+			return nil, errors.New("field " + field + " is invalid")
+		}
+		query.Fields = append(query.Fields, field)
+	}
+	return query, nil
+}
+
+// positive read a positive integer query parameter, 0 when missing
+func positive(c IHttpContext, key string) (int, error) {
+	text := c.Query(key)
+	if len(text) == 0 {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(text)
+	if err != nil || value < 0 {
+		return 0, errors.New(key + " must be a positive integer")
+	}
+	return value, nil
+}
+
+// split a comma separated list, ignoring empty items
+func split(text string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(text, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// cursor encode the sort key values of the last row of a page
+func cursor(values []interface{}) string {
+	data, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// keys decode this cursor, it holds a value for each sort key then the id
+func (q *Query) keys() ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, errors.New("cursor is invalid")
+	}
+	var values []interface{}
+	if err := json.Unmarshal(data, &values); err != nil || len(values) != len(q.Sort)+1 {
+		return nil, errors.New("cursor does not match sort")
+	}
+	return values, nil
+}
@@ -0,0 +1,173 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yroffin/go-boot-sqllite/core/models"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		url   string
+		query *Query
+		err   string
+	}{
+		{"/", &Query{}, ""},
+		{"/?limit=20&offset=5&cursor=abc", &Query{Limit: 20, Offset: 5, Cursor: "abc"}, ""},
+		{"/?sort=type,-extended.level,+name&fields=name,,extended.room", &Query{
+			Sort:   []Order{{Field: "type"}, {Field: "extended.level", Desc: true}, {Field: "name"}},
+			Fields: []string{"name", "extended.room"},
+		}, ""},
+		{"/?limit=-1", nil, "limit must be a positive integer"},
+		{"/?offset=x", nil, "offset must be a positive integer"},
+		{"/?sort=a%20b", nil, "sort field a b is invalid"},
+		{"/?fields=-name", nil, "field -name is invalid"},
+	}
+	for _, test := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", test.url, nil)
+		query, err := ParseQuery(c)
+		if len(test.err) > 0 {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: expected error %q, got %v", test.url, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(query, test.query) {
+			t.Errorf("%s: got %+v", test.url, query)
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	query := &Query{Sort: []Order{{Field: "type"}}, Cursor: cursor([]interface{}{"sensor", "id"})}
+	values, err := query.keys()
+	if err != nil || !reflect.DeepEqual(values, []interface{}{"sensor", "id"}) {
+		t.Errorf("got %v %v", values, err)
+	}
+	for _, text := range []string{"!", cursor([]interface{}{"id"}), cursor(nil)} {
+		query.Cursor = text
+		if _, err := query.keys(); err == nil {
+			t.Errorf("cursor %s accepted", text)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	store := memoryStore(t,
+		node("a", "sensor", map[string]interface{}{"level": 3}),
+		node("b", "sensor", map[string]interface{}{"level": 1}),
+		node("c", "actuator", map[string]interface{}{"level": 3}),
+		node("d", "sensor", nil),
+		node("e", "actuator", map[string]interface{}{"level": 2, "room": "hall"}),
+	)
+	defer store.PreDestroy("store")
+	tests := []struct {
+		name  string
+		query Query
+		// names of all pages, followed with cursors
+		names []string
+		// pages read, a full last page is followed by an empty one
+		pages int
+	}{
+		{"sort", Query{Sort: []Order{{Field: "name", Desc: true}}}, []string{"e", "d", "c", "b", "a"}, 1},
+		{"sort with nulls first", Query{Sort: []Order{{Field: "extended.level"}, {Field: "name"}}}, []string{"d", "b", "e", "a", "c"}, 1},
+		{"sort desc with nulls last", Query{Sort: []Order{{Field: "extended.level", Desc: true}, {Field: "name"}}}, []string{"a", "c", "e", "b", "d"}, 1},
+		{"cursor", Query{Sort: []Order{{Field: "extended.level"}, {Field: "name"}}, Limit: 2}, []string{"d", "b", "e", "a", "c"}, 3},
+		{"cursor desc", Query{Sort: []Order{{Field: "type", Desc: true}, {Field: "extended.level", Desc: true}}, Limit: 2}, []string{"a", "b", "d", "c", "e"}, 3},
+		{"offset", Query{Sort: []Order{{Field: "name"}}, Limit: 2, Offset: 3}, []string{"d", "e"}, 1},
+		{"filter", Query{Filter: &Filter{Field: "type", Op: "eq", Value: "actuator"}, Sort: []Order{{Field: "name"}}, Limit: 1}, []string{"c", "e"}, 3},
+	}
+	for _, test := range tests {
+		query := test.query
+		found := make([]string, 0)
+		pages := 0
+		for {
+			array := (&models.NodeBeans{}).New()
+			page, err := store.Select((&models.NodeBean{}).New(), array, &query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pages++
+			found = append(found, names(array)...)
+			if len(page.Next) == 0 || query.Offset > 0 || pages > 10 {
+				break
+			}
+			query.Cursor = page.Next
+		}
+		if pages != test.pages {
+			t.Errorf("%s: %d pages, expected %d", test.name, pages, test.pages)
+		}
+		if strings.Join(found, ",") != strings.Join(test.names, ",") {
+			t.Errorf("%s: found %v, expected %v", test.name, found, test.names)
+		}
+	}
+}
+
+func TestSelectTotal(t *testing.T) {
+	store := memoryStore(t, node("a", "sensor", nil), node("b", "sensor", nil), node("c", "actuator", nil))
+	defer store.PreDestroy("store")
+	page, err := store.Select((&models.NodeBean{}).New(), (&models.NodeBeans{}).New(), &Query{Filter: &Filter{Field: "type", Op: "eq", Value: "sensor"}, Limit: 1})
+	if err != nil || page.Total != 2 || len(page.Next) == 0 {
+		t.Errorf("got %+v %v", page, err)
+	}
+	if _, err := store.Select((&models.NodeBean{}).New(), (&models.NodeBeans{}).New(), &Query{Cursor: "!"}); KindOf(err) != Validation {
+		t.Errorf("invalid cursor answers %v", err)
+	}
+}
+
+func TestProjection(t *testing.T) {
+	bean := node("temp", "sensor", map[string]interface{}{"room": "kitchen", "level": 2})
+	store := memoryStore(t, bean)
+	defer store.PreDestroy("store")
+	array := (&models.NodeBeans{}).New()
+	if _, err := store.Select((&models.NodeBean{}).New(), array, &Query{Fields: []string{"name", "extended.room"}}); err != nil {
+		t.Fatal(err)
+	}
+	projection, ok := array.Get()[0].(*models.ProjectionBean)
+	expected := map[string]interface{}{"id": bean.GetID(), "name": "temp", "extended": map[string]interface{}{"room": "kitchen"}}
+	if !ok || !reflect.DeepEqual(projection.Fields, expected) {
+		t.Errorf("got %#v", array.Get()[0])
+	}
+}
+
+func TestSelectCorrupted(t *testing.T) {
+	bean := node("temp", "sensor", nil)
+	store := memoryStore(t, bean)
+	defer store.PreDestroy("store")
+	if _, err := store.db().Exec("UPDATE NodeBean SET json = ? WHERE id = ?", `{"name":1}`, bean.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Select((&models.NodeBean{}).New(), (&models.NodeBeans{}).New(), &Query{}); KindOf(err) != Internal || !strings.Contains(err.Error(), "corrupted") {
+		t.Errorf("got %v", err)
+	}
+}
@@ -23,9 +23,16 @@
 package engine
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
@@ -33,7 +40,7 @@ import (
 )
 
 func init() {
-	winter.Helper.Register("router", (&service{}).New())
+	winter.Define("router", func() winter.IBean { return (&service{}).New() })
 }
 
 // service internal members
@@ -43,16 +50,23 @@ type service struct {
 	engine *gin.Engine
 	// PackManager
 	box winter.PackManager
+	// running servers
+	servers []*http.Server
+	mutex   sync.Mutex
+	// DrainTimeout for pending requests on shutdown
+	DrainTimeout time.Duration `@value:"router.drain-timeout"`
 	// SwaggerService with injection mecanism
 	Swagger ISwaggerService `@autowired:"swagger"`
+	// Manager with injection mecanism (by type)
+	Manager winter.IManager `@autowired:""`
 }
 
 // IRouter Test all package methods
 type IRouter interface {
 	winter.IService
-	// Http boot
+	// Http boot, listen at once and serve in background
 	HTTP(port int) error
-	// Https boot
+	// Https boot, listen at once and serve in background
 	HTTPS(port int, certFile string, keyFile string) error
 	// Swagger
 	SwaggerModel() func(*gin.Context)
@@ -70,9 +84,10 @@ type IRouter interface {
 
 // New constructor
 func (p *service) New() IRouter {
-	bean := service{Service: &winter.Service{Bean: &winter.Bean{}}}
+	bean := service{Service: &winter.Service{Bean: &winter.Bean{}}, DrainTimeout: 10 * time.Second}
 	// define all routes
 	bean.engine = gin.Default()
+	bean.engine.Use(bean.RequestID(), bean.RequestContext(), bean.RequestScope())
 	return &bean
 }
 
@@ -83,6 +98,8 @@ func (p *service) Init() error {
 
 // PostConstruct Init this API
 func (p *service) PostConstruct(name string) error {
+	// shared by http and https servers
+	p.engine.GET("/api/swagger.json", p.SwaggerModel())
 	return nil
 }
 
@@ -127,6 +144,77 @@ func (p *service) Validate(name string) error {
 	return nil
 }
 
+// PreDestroy shutdown all servers, pending requests are drained
+func (p *service) PreDestroy(name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.DrainTimeout)
+	defer cancel()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var result error
+	for _, server := range p.servers {
+		err := server.Shutdown(ctx)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"addr":  server.Addr,
+				"error": err,
+			}).Error("PreDestroy")
+			result = err
+		}
+	}
+	p.servers = nil
+	return result
+}
+
+// RequestID keep the X-Request-Id of the request, or generate one, it is
+// also returned in response headers
+func (p *service) RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader("X-Request-Id")
+		if len(id) == 0 {
+			data := make([]byte, 16)
+			rand.Read(data)
+			id = hex.EncodeToString(data)
+		}
+		c.Set(RequestIDKey, id)
+		c.Header("X-Request-Id", id)
+		c.Next()
+	}
+}
+
+// RequestContext expose the context of the request, see API.Context, so
+// that queries and units of work stop when the client goes away
+func (p *service) RequestContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(ContextKey, c.Request.Context())
+		c.Next()
+	}
+}
+
+// limitBody fail reading the body of this request past limit bytes with a
+// http.MaxBytesError, bodies of other contexts than gin ones are not limited
+func limitBody(c IHttpContext, limit int64) {
+	if request, ok := c.(*gin.Context); ok && limit > 0 {
+		request.Request.Body = http.MaxBytesReader(request.Writer, request.Request.Body, limit)
+	}
+}
+
+// RequestScope release request scoped beans once the request is handled
+func (p *service) RequestScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if p.Manager == nil {
+			return
+		}
+		err := p.Manager.ReleaseRequest(c)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"path":  c.Request.URL.Path,
+				"error": err,
+			}).Error("RequestScope")
+		}
+	}
+}
+
 // Swagger method
 func (p *service) SwaggerModel() func(*gin.Context) {
 	anonymous := func(c *gin.Context) {
@@ -135,24 +223,60 @@ func (p *service) SwaggerModel() func(*gin.Context) {
 	return anonymous
 }
 
-// HTTP boot http service
+// HTTP start an http server, it runs until PreDestroy; a port which can
+// not be bound fails at once
 func (p *service) HTTP(port int) error {
 	gin.SetMode("debug")
 
-	p.engine.GET("/api/swagger.json", p.SwaggerModel())
-	p.engine.Run(":" + strconv.Itoa(port))
+	server := &http.Server{Addr: ":" + strconv.Itoa(port), Handler: p.engine}
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return err
+	}
+	p.serve(server, func() error {
+		return server.Serve(listener)
+	})
 	return nil
 }
 
-// HTTP boot http service
+// HTTPS start an https server, it runs until PreDestroy; a port which can
+// not be bound or unreadable certificates fail at once
 func (p *service) HTTPS(port int, certFile string, keyFile string) error {
 	gin.SetMode("debug")
 
-	p.engine.GET("/api/swagger.json", p.SwaggerModel())
-	p.engine.RunTLS(":"+strconv.Itoa(port), certFile, keyFile)
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Addr: ":" + strconv.Itoa(port), Handler: p.engine}
+	server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return err
+	}
+	p.serve(server, func() error {
+		return server.ServeTLS(listener, "", "")
+	})
 	return nil
 }
 
+// serve keep track of this listening server, so that PreDestroy shuts it
+// down, and serve it in background until shutdown
+func (p *service) serve(server *http.Server, listen func() error) {
+	p.mutex.Lock()
+	p.servers = append(p.servers, server)
+	p.mutex.Unlock()
+	go func() {
+		err := listen()
+		if err != nil && err != http.ErrServerClosed {
+			log.WithFields(log.Fields{
+				"addr":  server.Addr,
+				"error": err,
+			}).Error("Unable to serve")
+		}
+	}()
+}
+
 // HandleFunc declare a handler
 func (p *service) HandleFuncTonic(path string, f func() (interface{}, error), method string, content string) {
 	// declare it to the router
@@ -251,7 +375,7 @@ func (p *service) HandlerStaticJson(method func() (interface{}, error), code int
 		c.Header("Content-type", "text/html")
 		data, err := method()
 		if err != nil {
-			c.String(400, "{\"message\":\"\"}")
+			WriteProblem(c, "", err)
 			return
 		}
 		c.JSON(code, data)
@@ -312,7 +436,7 @@ func (p *service) HandlerStaticString(method func() (string, error), content str
 		c.Header("Content-type", "text/html")
 		data, err := method()
 		if err != nil {
-			c.String(400, "{\"message\":\"\"}")
+			WriteProblem(c, "", err)
 			return
 		}
 		c.String(200, data)
@@ -337,7 +461,7 @@ func (p *service) HandlerStaticStringWithId(method func(string) (string, error),
 		c.Header("Content-type", "text/html")
 		data, err := method(c.Param("id"))
 		if err != nil {
-			c.String(400, "{\"message\":\"\"}")
+			WriteProblem(c, "", err)
 			return
 		}
 		c.String(200, data)
@@ -0,0 +1,157 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// freePort a port nobody listens on
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestListen(t *testing.T) {
+	busy, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	tests := []struct {
+		name   string
+		listen func(router IRouter) error
+		err    bool
+	}{
+		{"free port", func(router IRouter) error { return router.HTTP(freePort(t)) }, false},
+		{"busy port", func(router IRouter) error { return router.HTTP(busy.Addr().(*net.TCPAddr).Port) }, true},
+		{"missing certificate", func(router IRouter) error { return router.HTTPS(freePort(t), "missing.pem", "missing.key") }, true},
+	}
+	for _, test := range tests {
+		router := (&service{}).New()
+		err := test.listen(router)
+		if test.err != (err != nil) {
+			t.Errorf("%s: got %v", test.name, err)
+		}
+		if err := router.PreDestroy("router"); err != nil {
+			t.Errorf("%s: shutdown %v", test.name, err)
+		}
+	}
+}
+
+func TestServeUntilShutdown(t *testing.T) {
+	router := (&service{}).New()
+	port := freePort(t)
+	if err := router.HTTP(port); err != nil {
+		t.Fatal(err)
+	}
+	// listening as soon as HTTP returns
+	url := "http://localhost:" + strconv.Itoa(port) + "/missing"
+	response, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("got %d", response.StatusCode)
+	}
+	if err := router.PreDestroy("router"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := http.Get(url); err == nil {
+		t.Error("still serving after shutdown")
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/api/graph/query", nil).WithContext(ctx)
+	(&service{}).RequestContext()(c)
+	got := (&API{}).Context(c)
+	// the client goes away
+	cancel()
+	select {
+	case <-got.Done():
+	default:
+		t.Error("request context not done")
+	}
+}
+
+func TestDrain(t *testing.T) {
+	tests := []struct {
+		name  string
+		drain time.Duration
+		// handler duration
+		work time.Duration
+		err  bool
+	}{
+		{"drained", 5 * time.Second, 100 * time.Millisecond, false},
+		{"drain timeout", 20 * time.Millisecond, 500 * time.Millisecond, true},
+	}
+	for _, test := range tests {
+		router := (&service{}).New().(*service)
+		router.DrainTimeout = test.drain
+		started := make(chan struct{})
+		router.HandleFunc("/slow", func(c IHttpContext) {
+			close(started)
+			time.Sleep(test.work)
+			c.String(200, "done")
+		}, "GET", "text/plain")
+		port := freePort(t)
+		if err := router.HTTP(port); err != nil {
+			t.Fatal(err)
+		}
+		status := make(chan int, 1)
+		go func() {
+			response, err := http.Get("http://localhost:" + strconv.Itoa(port) + "/slow")
+			if err != nil {
+				status <- 0
+				return
+			}
+			response.Body.Close()
+			status <- response.StatusCode
+		}()
+		<-started
+		// in flight requests are answered before the server stops
+		err := router.PreDestroy("router")
+		if test.err != (err != nil) {
+			t.Errorf("%s: got %v", test.name, err)
+		}
+		if code := <-status; !test.err && code != 200 {
+			t.Errorf("%s: in flight request answers %d", test.name, code)
+		}
+	}
+}
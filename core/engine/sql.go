@@ -23,6 +23,10 @@
 package engine
 
 import (
+	"context"
+	"encoding/json"
+	"reflect"
+
 	"github.com/yroffin/go-boot-sqllite/core/models"
 	"github.com/yroffin/go-boot-sqllite/core/winter"
 )
@@ -32,7 +36,11 @@ type SqlCrudBusiness struct {
 	// members
 	*winter.Service
 	// Store with injection mecanism
-	Store IDataStore `@autowired:"sqllite-manager"`
+	Store IDataStore `@autowired:""`
+	// Events with injection mecanism
+	Events winter.IEventBus `@autowired:"events"`
+	// Graph with injection mecanism, joined by units of work
+	Graph ILinkBusiness `@autowired:"graph-crud-business" @optional:"true"`
 }
 
 // New constructor
@@ -48,8 +56,7 @@ func (p *SqlCrudBusiness) Init() error {
 
 // Clear this bean
 func (p *SqlCrudBusiness) Clear(excp []string) error {
-	p.Store.Clear(excp)
-	return nil
+	return p.Store.Clear(excp)
 }
 
 // Statistics some statistics
@@ -69,42 +76,161 @@ func (p *SqlCrudBusiness) Validate(name string) error {
 
 // GetAll retrieve this bean by its id
 func (p *SqlCrudBusiness) GetAll(toGet models.IPersistent, toGets models.IPersistents) (models.IPersistents, error) {
-	p.Store.GetAll(toGet, toGets)
-	return toGets, nil
+	err := p.Store.GetAll(toGet, toGets)
+	return toGets, err
+}
+
+// Find retrieve all beans matching this filter
+func (p *SqlCrudBusiness) Find(toGet models.IPersistent, toGets models.IPersistents, filter *Filter) (models.IPersistents, error) {
+	err := p.Store.Find(toGet, toGets, filter)
+	return toGets, err
+}
+
+// Select retrieve a page of beans
+func (p *SqlCrudBusiness) Select(toGet models.IPersistent, toGets models.IPersistents, query *Query) (models.IPersistents, *Page, error) {
+	page, err := p.Store.Select(toGet, toGets, query)
+	return toGets, page, err
 }
 
 // Get retrieve this bean by its id
 func (p *SqlCrudBusiness) Get(toGet models.IPersistent) (models.IPersistent, error) {
-	p.Store.Get(toGet.GetID(), toGet)
-	return toGet, nil
+	err := p.Store.Get(toGet.GetID(), toGet)
+	return toGet, err
 }
 
 // Create create a new persistent bean
 func (p *SqlCrudBusiness) Create(toCreate models.IPersistent) (models.IPersistent, error) {
-	p.Store.Create(toCreate)
-	return toCreate, nil
+	if err := Validate(toCreate); err != nil {
+		return toCreate, err
+	}
+	err := p.Store.Create(toCreate)
+	if err == nil {
+		p.Events.Publish(EntityCreated{Entity: toCreate})
+	}
+	return toCreate, err
 }
 
 // Update an existing bean
 func (p *SqlCrudBusiness) Update(toUpdate models.IPersistent) (models.IPersistent, error) {
-	p.Store.Update(toUpdate.GetID(), toUpdate)
-	return toUpdate, nil
+	if err := Validate(toUpdate); err != nil {
+		return toUpdate, err
+	}
+	err := p.Store.Update(toUpdate.GetID(), toUpdate)
+	if err == nil {
+		p.Events.Publish(EntityUpdated{Entity: toUpdate})
+	}
+	return toUpdate, err
+}
+
+// Upsert update a bean, or create it with its id
+func (p *SqlCrudBusiness) Upsert(toUpsert models.IPersistent) (models.IPersistent, bool, error) {
+	if err := Validate(toUpsert); err != nil {
+		return toUpsert, false, err
+	}
+	created, err := p.Store.Upsert(toUpsert.GetID(), toUpsert)
+	if err == nil && created {
+		p.Events.Publish(EntityCreated{Entity: toUpsert})
+	} else if err == nil {
+		p.Events.Publish(EntityUpdated{Entity: toUpsert})
+	}
+	return toUpsert, created, err
 }
 
 // Delete a bean
 func (p *SqlCrudBusiness) Delete(toDelete models.IPersistent) (models.IPersistent, error) {
-	p.Store.Delete(toDelete.GetID(), toDelete)
-	return toDelete, nil
+	err := p.Store.Delete(toDelete.GetID(), toDelete)
+	if err == nil {
+		p.Events.Publish(EntityDeleted{Entity: toDelete})
+	}
+	return toDelete, err
 }
 
 // Delete a bean
 func (p *SqlCrudBusiness) Truncate(toTruncate models.IPersistent) (models.IPersistent, error) {
-	p.Store.Truncate(toTruncate)
-	return toTruncate, nil
+	err := p.Store.Truncate(toTruncate)
+	return toTruncate, err
+}
+
+// Patch a stored bean, toPatch receives the patched bean
+func (p *SqlCrudBusiness) Patch(toPatch models.IPersistent, patch Patcher) (models.IPersistent, error) {
+	err := p.Store.Patch(toPatch.GetID(), toPatch, p.validated(toPatch, patch))
+	if err == nil {
+		p.Events.Publish(EntityUpdated{Entity: toPatch})
+	}
+	return toPatch, err
+}
+
+// Bulk apply these operations in the unit of work of ctx, each one in its
+// own savepoint; an atomic bulk is rolled back on the first failure,
+// otherwise failed operations only keep their error, unless a delete failed
+// once its links were released. Events are published once committed
+func (p *SqlCrudBusiness) Bulk(ctx context.Context, operations []*Operation, atomic bool) error {
+	return p.Work(ctx, func(ctx context.Context) error {
+		unit, _ := UnitOf(ctx)
+		crud, ok := sqlCrud(unit.Crud)
+		if !ok {
+			return internal("", "bulk needs a sql unit of work", nil)
+		}
+		for _, operation := range operations {
+			operation.Err = crud.Store.Transaction(func(store IDataStore) error {
+				return operation.apply(ctx, store)
+			})
+			if operation.Err != nil && (atomic || operation.released) {
+				return operation.Err
+			}
+		}
+		for _, operation := range operations {
+			if operation.Err == nil {
+				crud.Events.Publish(operation.event())
+			}
+		}
+		return nil
+	})
+}
+
+// Work run fn in a unit of work found in its context with UnitOf, entities
+// are committed in a single sql transaction once fn succeeded, links are
+// undone if fn or this commit fails; fn joins the unit of ctx if any
+func (p *SqlCrudBusiness) Work(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := UnitOf(ctx); ok {
+		return fn(ctx)
+	}
+	events := &deferred{IEventBus: p.Events}
+	work := func(links ILinkBusiness) error {
+		err := p.Store.Transaction(func(store IDataStore) error {
+			crud := &SqlCrudBusiness{Service: p.Service, Store: store, Events: events, Graph: links}
+			return fn(WithUnit(ctx, &Unit{Crud: crud, Links: links}))
+		})
+		if err == nil {
+			events.flush()
+		}
+		return err
+	}
+	if p.Graph == nil {
+		return work(nil)
+	}
+	return p.Graph.Transaction(work)
 }
 
-// Patch a bean
-func (p *SqlCrudBusiness) Patch(toPatch models.IPersistent) (models.IPersistent, error) {
-	p.Store.Update(toPatch.GetID(), toPatch)
-	return toPatch, nil
+// validated check the patched document before it is written, in a fresh
+// bean so that removed members are really missing
+func (p *SqlCrudBusiness) validated(toPatch models.IPersistent, patch Patcher) Patcher {
+	return func(document []byte) ([]byte, error) {
+		patched, err := patch(document)
+		if err != nil {
+			return nil, err
+		}
+		typ := reflect.TypeOf(toPatch)
+		if typ.Kind() != reflect.Ptr {
+			return patched, nil
+		}
+		check := reflect.New(typ.Elem()).Interface().(models.IPersistent)
+		if err := json.Unmarshal(patched, check); err != nil {
+			return nil, invalid(toPatch.GetEntityName(), "malformed patched entity", err)
+		}
+		if err := Validate(check); err != nil {
+			return nil, err
+		}
+		return patched, nil
+	}
 }
@@ -26,14 +26,17 @@ import (
 	"crypto/rand"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 
-	// for import driver
-	_ "github.com/mattn/go-sqlite3"
+	// sqlite driver
+	sqlite3 "github.com/mattn/go-sqlite3"
 
 	"github.com/yroffin/go-boot-sqllite/core/models"
 	"github.com/yroffin/go-boot-sqllite/core/winter"
@@ -44,17 +47,23 @@ type Store struct {
 	*winter.Service
 	// Store SQL lite
 	database *sql.DB
+	// tx of a store bound to a transaction, see Transaction
+	tx *sql.Tx
+	// depth of nested transactions, they are savepoints
+	depth int
 	// Tables
 	Tables []string
 	// Db path
-	DbPath string
+	DbPath string `@value:"store.path" @default:"./sqllite.db"`
 	// Manager with injection mecanism
 	APIManager IAPIManager `@autowired:"APIManager"`
+	// All APIs with injection mecanism (by type)
+	APIs []IAPI `@autowired:""`
 }
 
 // New constructor
-func (p *Store) New(dbpath string) IDataStore {
-	bean := Store{Service: &winter.Service{Bean: &winter.Bean{}}, DbPath: dbpath}
+func (p *Store) New() IDataStore {
+	bean := Store{Service: &winter.Service{Bean: &winter.Bean{}}}
 	return &bean
 }
 
@@ -69,8 +78,10 @@ func (p *Store) Clear(excp []string) error {
 	for i := 0; i < len(p.Tables); i++ {
 		if p.Tables[i] != excp[0] {
 			// prepare statement
-			statement, _ := p.database.Prepare("DELETE FROM " + p.Tables[i])
-			statement.Exec()
+			var query = "DELETE FROM " + p.Tables[i]
+			if _, err := p.database.Exec(query); err != nil {
+				return p.failure(p.Tables[i], query, err)
+			}
 		}
 	}
 
@@ -83,10 +94,10 @@ func (p *Store) Statistics() ([]IStats, error) {
 	// truncate all tables
 	for i := 0; i < len(p.Tables); i++ {
 		// prepare statement
-		rows, _ := p.database.Query("SELECT COUNT (1) FROM " + p.Tables[i])
+		var query = "SELECT COUNT (1) FROM " + p.Tables[i]
 		var count string
-		for rows.Next() {
-			rows.Scan(&count)
+		if err := p.database.QueryRow(query).Scan(&count); err != nil {
+			return nil, p.failure(p.Tables[i], query, err)
 		}
 		stat := StoreStats{}
 		stat.Key = p.Tables[i] + ".count"
@@ -102,19 +113,18 @@ func (p *Store) PostConstruct(name string) error {
 	p.Tables = make([]string, 0)
 
 	// Create database
-	database, _ := sql.Open("sqlite3", p.DbPath)
-	p.database = database
+	if err := p.open(); err != nil {
+		return err
+	}
 
 	// create all tables
-	winter.Helper.ForEach(func(bean interface{}) {
-		assert, ok := bean.(IAPI)
-		if ok && assert != nil && assert.GetFactory() != nil {
-			// prepare statement
-			statement, _ := p.database.Prepare("CREATE TABLE IF NOT EXISTS " + assert.GetFactory().GetEntityName() + " (id TEXT NOT NULL PRIMARY KEY, json JSONB)")
-			statement.Exec()
-			p.Tables = append(p.Tables, assert.GetFactory().GetEntityName())
+	for _, api := range p.APIs {
+		if api.GetFactory() != nil {
+			if err := p.table(api.GetFactory().GetEntityName()); err != nil {
+				return err
+			}
 		}
-	})
+	}
 
 	log.WithFields(log.Fields{
 		"tables": p.Tables,
@@ -122,11 +132,92 @@ func (p *Store) PostConstruct(name string) error {
 	return nil
 }
 
+// open the database, filters, sorts and projections need its JSON1
+// extension; transactions begin immediate, so that concurrent read then
+// write transactions wait for each other instead of failing as busy
+func (p *Store) open() error {
+	database, err := sql.Open("sqlite3", dsn(p.DbPath))
+	if err != nil {
+		return err
+	}
+	p.database = database
+	var text string
+	if err := p.database.QueryRow("SELECT json('{}')").Scan(&text); err != nil {
+		return errors.New("sqlite3 has no JSON1 extension, build with -tags json1 (" + err.Error() + ")")
+	}
+	return nil
+}
+
+// dsn of this database path, with immediate transactions
+func dsn(path string) string {
+	if strings.Contains(path, "_txlock=") {
+		return path
+	}
+	if strings.Contains(path, "?") {
+		return path + "&_txlock=immediate"
+	}
+	return path + "?_txlock=immediate"
+}
+
+// table create the table of this entity if needed
+func (p *Store) table(entityName string) error {
+	_, err := p.database.Exec("CREATE TABLE IF NOT EXISTS " + entityName + " (id TEXT NOT NULL PRIMARY KEY, json JSONB, version INTEGER NOT NULL DEFAULT 1)")
+	if err != nil {
+		return err
+	}
+	if err = p.migrate(entityName); err != nil {
+		return err
+	}
+	p.Tables = append(p.Tables, entityName)
+	return nil
+}
+
+// migrate add the version column to tables created without it
+func (p *Store) migrate(table string) error {
+	rows, err := p.database.Query("PRAGMA table_info(" + table + ")")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var cid, notNull, pk int
+	var name, typ string
+	var value interface{}
+	for rows.Next() {
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &value, &pk); err != nil {
+			return err
+		}
+		if name == "version" {
+			return nil
+		}
+	}
+	rows.Close()
+	log.WithFields(log.Fields{
+		"table": table,
+	}).Info("Add version column")
+	_, err = p.database.Exec("ALTER TABLE " + table + " ADD COLUMN version INTEGER NOT NULL DEFAULT 1")
+	return err
+}
+
 // Validate Init this bean
 func (p *Store) Validate(name string) error {
 	return nil
 }
 
+// PreDestroy close this database
+func (p *Store) PreDestroy(name string) error {
+	if p.database == nil {
+		return nil
+	}
+	err := p.database.Close()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"path":  p.DbPath,
+			"error": err,
+		}).Error("PreDestroy")
+	}
+	return err
+}
+
 // uuid generates a random UUID according to RFC 4122
 func (p *Store) uuid(entity interface{}) (string, error) {
 	uuid := make([]byte, 16)
@@ -142,6 +233,100 @@ func (p *Store) uuid(entity interface{}) (string, error) {
 	return text, nil
 }
 
+// executor common to database and transactions
+type executor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// db the current transaction if any, or the database
+func (p *Store) db() executor {
+	if p.tx != nil {
+		return p.tx
+	}
+	return p.database
+}
+
+// Transaction run fn with a store bound to a single transaction, committed
+// if fn succeeds and rolled back otherwise, or if it panics; transactions
+// of a bound store are savepoints, so a failure only rolls back its own
+// changes
+func (p *Store) Transaction(fn func(IDataStore) error) error {
+	bound := *p
+	bound.depth = p.depth + 1
+	if p.tx == nil {
+		tx, err := p.database.Begin()
+		if err != nil {
+			return p.failure("", "BEGIN", err)
+		}
+		bound.tx = tx
+		defer func() {
+			if r := recover(); r != nil {
+				tx.Rollback()
+				panic(r)
+			}
+		}()
+		if err := fn(&bound); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return p.failure("", "COMMIT", err)
+		}
+		return nil
+	}
+	var savepoint = "s" + strconv.Itoa(bound.depth)
+	if _, err := p.tx.Exec("SAVEPOINT " + savepoint); err != nil {
+		return p.failure("", "SAVEPOINT", err)
+	}
+	if err := fn(&bound); err != nil {
+		p.tx.Exec("ROLLBACK TO " + savepoint)
+		p.tx.Exec("RELEASE " + savepoint)
+		return err
+	}
+	if _, err := p.tx.Exec("RELEASE " + savepoint); err != nil {
+		return p.failure("", "RELEASE", err)
+	}
+	return nil
+}
+
+// expected version of this bean, 0 when unknown or not versioned
+func (p *Store) expected(entity models.IPersistent) int64 {
+	if versioned, ok := entity.(models.IVersioned); ok {
+		return versioned.GetVersion()
+	}
+	return 0
+}
+
+// stamp this bean with its stored version
+func (p *Store) stamp(entity models.IPersistent, version int64) {
+	if versioned, ok := entity.(models.IVersioned); ok {
+		versioned.SetVersion(version)
+	}
+}
+
+// missing error when no row matched, an expected version fails as a
+// precondition even if the row is gone
+func (p *Store) missing(entityName string, id string, expected int64) error {
+	if expected > 0 {
+		return stale(entityName, id, expected)
+	}
+	return notFound(entityName, id)
+}
+
+// failure type a database error, constraint violations are conflicts
+func (p *Store) failure(entity string, query string, err error) error {
+	log.WithFields(log.Fields{
+		"sql":   query,
+		"error": err,
+	}).Error("Store")
+	if sqlError, ok := err.(sqlite3.Error); ok && sqlError.Code == sqlite3.ErrConstraint {
+		return conflict(entity, "constraint violation", err)
+	}
+	return internal(entity, "database failure", err)
+}
+
 // Create this persistent bean n store
 func (p *Store) Create(entity models.IPersistent) error {
 	// get entity name
@@ -149,35 +334,132 @@ func (p *Store) Create(entity models.IPersistent) error {
 	// Fix timestamp
 	entity.SetTimestamp(models.JSONTime(time.Now()))
 	// fix UUID
-	uuid, _ := p.uuid(entity)
-	entity.SetID(uuid)
+	uuid, err := p.uuid(entity)
+	if err != nil {
+		return internal(entityName, "no uuid", err)
+	}
+	return p.insert(uuid, entity)
+}
+
+// insert this persistent bean with this id, an existing id is a conflict
+func (p *Store) insert(id string, entity models.IPersistent) error {
+	// get entity name
+	var entityName = entity.GetEntityName()
+	entity.SetID(id)
 	// insert
-	statement, _ := p.database.Prepare("INSERT INTO " + entityName + " (id, json) VALUES (?,?)")
-	data, _ := json.Marshal(entity)
-	statement.Exec(uuid, string(data))
+	var query = "INSERT INTO " + entityName + " (id, json, version) VALUES (?,?,1)"
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return invalid(entityName, "can not be marshalled", err)
+	}
+	if _, err := p.db().Exec(query, id, string(data)); err != nil {
+		return p.failure(entityName, query, err)
+	}
+	p.stamp(entity, 1)
 	return nil
 }
 
-// Update this persistent bean
+// Update this persistent bean, its new version is read back in the same
+// transaction
 func (p *Store) Update(id string, entity models.IPersistent) error {
+	return p.Transaction(func(store IDataStore) error {
+		return store.(*Store).update(id, entity)
+	})
+}
+
+// update in the current transaction
+func (p *Store) update(id string, entity models.IPersistent) error {
 	// get entity name
 	var entityName = entity.GetEntityName()
 	// Fix timestamp
 	entity.SetTimestamp(models.JSONTime(time.Now()))
 	// Fix ID
 	entity.SetID(id)
-	// prepare statement
-	statement, _ := p.database.Prepare("UPDATE " + entityName + " SET json = ? WHERE id = ?")
-	data, _ := json.Marshal(entity)
-	res, _ := statement.Exec(string(data), id)
+	// prepare statement, with the expected version if any
+	var query = "UPDATE " + entityName + " SET json = ?, version = version + 1 WHERE id = ?"
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return invalid(entityName, "can not be marshalled", err)
+	}
+	args := []interface{}{string(data), id}
+	var expected = p.expected(entity)
+	if expected > 0 {
+		query = query + " AND version = ?"
+		args = append(args, expected)
+	}
+	res, err := p.tx.Exec(query, args...)
+	if err != nil {
+		return p.failure(entityName, query, err)
+	}
 	rowAffected, _ := res.RowsAffected()
 	if rowAffected == 0 {
-		log.WithFields(log.Fields{
-			"id":       id,
-			"affected": rowAffected,
-			"sql":      "UPDATE " + entityName + " SET json = ? WHERE id = ?",
-		}).Info("Affected row(s)")
+		return p.missing(entityName, id, expected)
+	}
+	var version int64
+	var read = "SELECT version FROM " + entityName + " WHERE id = ?"
+	if err := p.tx.QueryRow(read, id).Scan(&version); err != nil {
+		return p.failure(entityName, read, err)
+	}
+	p.stamp(entity, version)
+	return nil
+}
+
+// Upsert update this persistent bean, or create it with this id when
+// missing, created is true in that case
+func (p *Store) Upsert(id string, entity models.IPersistent) (bool, error) {
+	err := p.Update(id, entity)
+	if KindOf(err) != NotFound {
+		return false, err
+	}
+	p.stamp(entity, 0)
+	return true, p.insert(id, entity)
+}
+
+// Patch load the stored document of this bean, patch it and write it
+// back in a single transaction, entity receives the patched bean
+func (p *Store) Patch(id string, entity models.IPersistent, patch Patcher) error {
+	return p.Transaction(func(store IDataStore) error {
+		return store.(*Store).patch(id, entity, patch)
+	})
+}
+
+// patch in the current transaction
+func (p *Store) patch(id string, entity models.IPersistent, patch Patcher) error {
+	// get entity name
+	var entityName = entity.GetEntityName()
+	var query = "SELECT json, version FROM " + entityName + " WHERE id = ?"
+	var data string
+	var version int64
+	var expected = p.expected(entity)
+	err := p.tx.QueryRow(query, id).Scan(&data, &version)
+	if err == sql.ErrNoRows {
+		return p.missing(entityName, id, expected)
+	}
+	if err != nil {
+		return p.failure(entityName, query, err)
+	}
+	if expected > 0 && expected != version {
+		return stale(entityName, id, expected)
+	}
+	patched, err := patch([]byte(data))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(patched, entity); err != nil {
+		return invalid(entityName, "patched entity is invalid", err)
 	}
+	// Fix ID and timestamp
+	entity.SetID(id)
+	entity.SetTimestamp(models.JSONTime(time.Now()))
+	bin, err := json.Marshal(entity)
+	if err != nil {
+		return invalid(entityName, "can not be marshalled", err)
+	}
+	query = "UPDATE " + entityName + " SET json = ?, version = ? WHERE id = ?"
+	if _, err := p.tx.Exec(query, string(bin), version+1, id); err != nil {
+		return p.failure(entityName, query, err)
+	}
+	p.stamp(entity, version+1)
 	return nil
 }
 
@@ -187,16 +469,21 @@ func (p *Store) Delete(id string, entity models.IPersistent) error {
 	var entityName = entity.GetEntityName()
 	// Fix ID
 	entity.SetID(id)
-	// prepare statement
-	statement, _ := p.database.Prepare("DELETE FROM " + entityName + " WHERE id = ?")
-	res, _ := statement.Exec(id)
+	// prepare statement, with the expected version if any
+	var query = "DELETE FROM " + entityName + " WHERE id = ?"
+	args := []interface{}{id}
+	var expected = p.expected(entity)
+	if expected > 0 {
+		query = query + " AND version = ?"
+		args = append(args, expected)
+	}
+	res, err := p.db().Exec(query, args...)
+	if err != nil {
+		return p.failure(entityName, query, err)
+	}
 	rowAffected, _ := res.RowsAffected()
 	if rowAffected == 0 {
-		log.WithFields(log.Fields{
-			"id":       id,
-			"affected": rowAffected,
-			"sql":      "DELETE FROM " + entityName + " WHERE id = ?",
-		}).Info("Affected row(s)")
+		return p.missing(entityName, id, expected)
 	}
 	return nil
 }
@@ -206,8 +493,11 @@ func (p *Store) Truncate(entity models.IPersistent) error {
 	// get entity name
 	var entityName = entity.GetEntityName()
 	// prepare statement
-	statement, _ := p.database.Prepare("DELETE FROM " + entityName)
-	res, _ := statement.Exec()
+	var query = "DELETE FROM " + entityName
+	res, err := p.db().Exec(query)
+	if err != nil {
+		return p.failure(entityName, query, err)
+	}
 	rowAffected, _ := res.RowsAffected()
 	if rowAffected == 0 {
 		log.WithFields(log.Fields{
@@ -223,40 +513,251 @@ func (p *Store) Get(id string, entity models.IPersistent) error {
 	// get entity name
 	var entityName = entity.GetEntityName()
 	// prepare statement
-	rows, _ := p.database.Query("SELECT id, json FROM "+entityName+" WHERE id = ?", &id)
+	var query = "SELECT id, json, version FROM " + entityName + " WHERE id = ?"
+	rows, err := p.db().Query(query, &id)
+	if err != nil {
+		return p.failure(entityName, query, err)
+	}
+	defer rows.Close()
 	var data string
-	for rows.Next() {
-		rows.Scan(&id, &data)
-		var bin = []byte(data)
-		entity.SetID(id)
-		json.Unmarshal(bin, entity)
+	var version int64
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return p.failure(entityName, query, err)
+		}
+		return notFound(entityName, id)
+	}
+	if err := rows.Scan(&id, &data, &version); err != nil {
+		return p.failure(entityName, query, err)
+	}
+	p.stamp(entity, version)
+	var bin = []byte(data)
+	entity.SetID(id)
+	if err := json.Unmarshal(bin, entity); err != nil {
+		return internal(entityName, "stored entity "+id+" is corrupted", err)
 	}
 	return nil
 }
 
 // GetAll this persistent bean
 func (p *Store) GetAll(entity models.IPersistent, array models.IPersistents) error {
+	return p.Find(entity, array, &Filter{})
+}
+
+// Find all persistent beans matching this filter, it is compiled to
+// a json_extract where clause
+func (p *Store) Find(entity models.IPersistent, array models.IPersistents, filter *Filter) error {
+	_, err := p.Select(entity, array, &Query{Filter: filter})
+	return err
+}
+
+// Select a page of persistent beans, filter, sort, pagination and
+// projection are all done by sqlite
+func (p *Store) Select(entity models.IPersistent, array models.IPersistents, query *Query) (*Page, error) {
 	// get entity name
 	var entityName = entity.GetEntityName()
-	// prepare statement
-	var query = "SELECT id, json FROM " + entityName
-	rows, err := p.database.Query(query)
+	page := &Page{}
+	// where clauses
+	where, args := p.where(query.Filter)
+	if len(where) == 0 {
+		where = "1"
+	}
+	// total count
+	var count = "SELECT COUNT(1) FROM " + entityName + " WHERE " + where
+	if err := p.db().QueryRow(count, args...).Scan(&page.Total); err != nil {
+		return nil, p.failure(entityName, count, err)
+	}
+	if len(query.Cursor) > 0 {
+		values, err := query.keys()
+		if err != nil {
+			return nil, invalid(entityName, err.Error(), nil)
+		}
+		after, keys := p.after(query.Sort, values)
+		where = "(" + where + ") AND (" + after + ")"
+		args = append(args, keys...)
+	}
+	// selected columns, sort keys are also selected for the next cursor
+	var columns = "json"
+	selected := make([]interface{}, 0)
+	if len(query.Fields) > 0 {
+		pairs := make([]string, 0, len(query.Fields))
+		for _, field := range query.Fields {
+			pairs = append(pairs, "?, json_extract(json, ?)")
+			selected = append(selected, field, "$."+field)
+		}
+		columns = "json_object(" + strings.Join(pairs, ", ") + ")"
+	}
+	orders := make([]string, 0, len(query.Sort)+1)
+	for index, order := range query.Sort {
+		var key = "s" + strconv.Itoa(index)
+		columns = columns + ", json_extract(json, ?) AS " + key
+		selected = append(selected, "$."+order.Field)
+		if order.Desc {
+			key = key + " DESC"
+		}
+		orders = append(orders, key)
+	}
+	orders = append(orders, "id")
+	var limit = -1
+	if query.Limit > 0 {
+		limit = query.Limit
+	}
+	var offset = query.Offset
+	if len(query.Cursor) > 0 {
+		offset = 0
+	}
+	var statement = "SELECT id, version, " + columns + " FROM " + entityName + " WHERE " + where + " ORDER BY " + strings.Join(orders, ", ") + " LIMIT ? OFFSET ?"
+	rows, err := p.db().Query(statement, append(append(selected, args...), limit, offset)...)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"sql":   query,
-			"error": err,
-		}).Error("While retrrieve row(s)")
-		return err
+		return nil, p.failure(entityName, statement, err)
 	}
+	defer rows.Close()
 	var id string
 	var data string
+	var version int64
+	var last []interface{}
+	var rowCount = 0
 	for rows.Next() {
-		rows.Scan(&id, &data)
+		last = make([]interface{}, len(query.Sort)+1)
+		dest := []interface{}{&id, &version, &data}
+		for index := range query.Sort {
+			dest = append(dest, &last[index])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, p.failure(entityName, statement, err)
+		}
+		last[len(query.Sort)] = id
+		rowCount++
 		var bin = []byte(data)
-		copy := entity.Copy()
-		json.Unmarshal(bin, &copy)
-		copy.SetID(id)
-		array.Add(copy)
+		if len(query.Fields) > 0 {
+			fields, err := p.nest(bin, id)
+			if err != nil {
+				return nil, internal(entityName, "stored entity "+id+" is corrupted", err)
+			}
+			array.Add((&models.ProjectionBean{}).New(entityName, fields))
+			continue
+		}
+		bean := entity.Copy()
+		if err := json.Unmarshal(bin, bean); err != nil {
+			return nil, internal(entityName, "stored entity "+id+" is corrupted", err)
+		}
+		bean.SetID(id)
+		p.stamp(bean, version)
+		array.Add(bean)
 	}
-	return nil
+	if err := rows.Err(); err != nil {
+		return nil, p.failure(entityName, statement, err)
+	}
+	if query.Limit > 0 && rowCount == query.Limit {
+		page.Next = cursor(last)
+	}
+	return page, nil
+}
+
+// nest dotted projection keys, extended.room becomes {"extended":{"room":...}}
+func (p *Store) nest(data []byte, id string) (map[string]interface{}, error) {
+	var flat map[string]interface{}
+	if err := json.Unmarshal(data, &flat); err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{"id": id}
+	for key, value := range flat {
+		var parent = fields
+		path := strings.Split(key, ".")
+		for _, name := range path[:len(path)-1] {
+			child, ok := parent[name].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				parent[name] = child
+			}
+			parent = child
+		}
+		parent[path[len(path)-1]] = value
+	}
+	return fields, nil
+}
+
+// after compile the keyset condition of rows following a cursor, sort
+// keys then id; sqlite sorts null values first
+func (p *Store) after(sort []Order, values []interface{}) (string, []interface{}) {
+	args := make([]interface{}, 0)
+	clauses := make([]string, 0, len(values))
+	for index := range values {
+		terms := make([]string, 0, index+1)
+		for previous := 0; previous < index; previous++ {
+			term, params := p.key(sort, values, previous, "=")
+			terms = append(terms, term)
+			args = append(args, params...)
+		}
+		term, params := p.key(sort, values, index, ">")
+		terms = append(terms, term)
+		args = append(args, params...)
+		clauses = append(clauses, "("+strings.Join(terms, " AND ")+")")
+	}
+	return strings.Join(clauses, " OR "), args
+}
+
+// key compare a sort key to its cursor value, with = for equality or >
+// for the rows following it
+func (p *Store) key(sort []Order, values []interface{}, index int, compare string) (string, []interface{}) {
+	var expr = "id"
+	args := make([]interface{}, 0)
+	var desc = false
+	if index < len(sort) {
+		expr = "json_extract(json, ?)"
+		args = append(args, "$."+sort[index].Field)
+		desc = sort[index].Desc
+	}
+	var value = values[index]
+	switch {
+	case compare == "=" && value == nil:
+		return expr + " IS NULL", args
+	case compare == "=":
+		return expr + " = ?", append(args, value)
+	case value == nil && !desc:
+		return expr + " IS NOT NULL", args
+	case value == nil:
+		return "0", nil
+	case !desc:
+		return expr + " > ?", append(args, value)
+	}
+	return "(" + expr + " < ? OR " + expr + " IS NULL)", append(append(args, value), args...)
+}
+
+// where compile a checked filter, an empty filter has no clause
+func (p *Store) where(filter *Filter) (string, []interface{}) {
+	args := make([]interface{}, 0)
+	if filter == nil {
+		return "", args
+	}
+	if len(filter.Field) > 0 {
+		var path = "$." + filter.Field
+		switch {
+		case filter.Value == nil && filter.Op == "eq":
+			return "json_extract(json, ?) IS NULL", append(args, path)
+		case filter.Value == nil && filter.Op == "ne":
+			return "json_extract(json, ?) IS NOT NULL", append(args, path)
+		case filter.Op == "in":
+			values := filter.Value.([]interface{})
+			args = append(args, path)
+			args = append(args, values...)
+			return "json_extract(json, ?) IN (?" + strings.Repeat(",?", len(values)-1) + ")", args
+		}
+		return "json_extract(json, ?) " + filterOps[filter.Op] + " ?", append(args, path, filter.Value)
+	}
+	var children = filter.And
+	var operator = " AND "
+	if len(filter.Or) > 0 {
+		children = filter.Or
+		operator = " OR "
+	}
+	clauses := make([]string, 0, len(children))
+	for _, child := range children {
+		clause, values := p.where(child)
+		if len(clause) > 0 {
+			clauses = append(clauses, "("+clause+")")
+			args = append(args, values...)
+		}
+	}
+	return strings.Join(clauses, operator), args
 }
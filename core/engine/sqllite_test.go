@@ -0,0 +1,118 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/yroffin/go-boot-sqllite/core/models"
+)
+
+func TestDSN(t *testing.T) {
+	tests := []struct {
+		path string
+		dsn  string
+	}{
+		{"./sqllite.db", "./sqllite.db?_txlock=immediate"},
+		{"file:test?mode=memory", "file:test?mode=memory&_txlock=immediate"},
+		{"file:test?_txlock=exclusive", "file:test?_txlock=exclusive"},
+	}
+	for _, test := range tests {
+		if dsn := dsn(test.path); dsn != test.dsn {
+			t.Errorf("%s: got %s", test.path, dsn)
+		}
+	}
+}
+
+func TestConcurrentTransactions(t *testing.T) {
+	store := (&Store{}).New().(*Store)
+	store.DbPath = filepath.Join(t.TempDir(), "store.db")
+	if err := store.open(); err != nil {
+		t.Fatal(err)
+	}
+	defer store.PreDestroy("store")
+	if err := store.table("NodeBean"); err != nil {
+		t.Fatal(err)
+	}
+	bean := node("counter", "", map[string]interface{}{"count": 0})
+	if err := store.Create(bean); err != nil {
+		t.Fatal(err)
+	}
+	var wait sync.WaitGroup
+	errs := make(chan error, 100)
+	for worker := 0; worker < 10; worker++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for index := 0; index < 10; index++ {
+				// read then write, like Work and Patch
+				errs <- store.Transaction(func(bound IDataStore) error {
+					counter := (&models.NodeBean{}).New().(*models.NodeBean)
+					if err := bound.Get(bean.ID, counter); err != nil {
+						return err
+					}
+					counter.Extended["count"] = counter.Extended["count"].(float64) + 1
+					return bound.Update(bean.ID, counter)
+				})
+			}
+		}()
+	}
+	wait.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	counter := (&models.NodeBean{}).New().(*models.NodeBean)
+	if err := store.Get(bean.ID, counter); err != nil || counter.Extended["count"] != 100.0 || counter.Version != 101 {
+		t.Errorf("got %v version %d %v", counter.Extended, counter.Version, err)
+	}
+}
+
+func TestTransactionPanic(t *testing.T) {
+	store := memoryStore(t)
+	defer store.PreDestroy("store")
+	func() {
+		defer func() {
+			if r := recover(); r != "failure" {
+				t.Errorf("got %v", r)
+			}
+		}()
+		store.Transaction(func(bound IDataStore) error {
+			if err := bound.Create(node("lost", "", nil)); err != nil {
+				t.Fatal(err)
+			}
+			panic("failure")
+		})
+	}()
+	// the connection is released and nothing was written
+	nodes := (&models.NodeBeans{}).New()
+	if err := store.Transaction(func(bound IDataStore) error {
+		return bound.GetAll(&models.NodeBean{}, nodes)
+	}); err != nil || len(nodes.Get()) != 0 {
+		t.Errorf("got %v %v", names(nodes), err)
+	}
+}
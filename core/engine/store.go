@@ -23,6 +23,7 @@
 package engine
 
 import (
+	"context"
 
 	// for import driver
 	_ "github.com/mattn/go-sqlite3"
@@ -69,12 +70,18 @@ type IDataStore interface {
 	winter.IBean
 	Create(entity models.IPersistent) error
 	Update(id string, entity models.IPersistent) error
+	Upsert(id string, entity models.IPersistent) (bool, error)
+	Patch(id string, entity models.IPersistent, patch Patcher) error
 	Delete(id string, entity models.IPersistent) error
 	Truncate(entity models.IPersistent) error
 	Get(id string, entity models.IPersistent) error
 	GetAll(entity models.IPersistent, array models.IPersistents) error
+	Find(entity models.IPersistent, array models.IPersistents, filter *Filter) error
+	Select(entity models.IPersistent, array models.IPersistents, query *Query) (*Page, error)
 	Clear([]string) error
 	Statistics() ([]IStats, error)
+	// Transaction run fn with a store bound to a single transaction
+	Transaction(fn func(IDataStore) error) error
 }
 
 // IGraphStore interface
@@ -86,8 +93,14 @@ type IGraphStore interface {
 	TruncateLink(entity models.IPersistent) error
 	GetLink(entity models.IEdgeBean) error
 	GetAllLink(model string, id string, collection *[]models.IEdgeBean, targetType string) error
+	GetAllLinkTo(model string, id string, collection *[]models.IEdgeBean) error
+	Out(nodes []string) ([]models.IEdgeBean, error)
+	// Query run a read only gizmo query, at most limit results
+	Query(ctx context.Context, text string, limit int) ([]map[string]interface{}, error)
 	Clear() error
 	All() ([]IQuad, error)
 	Statistics() ([]IStats, error)
 	Export() (map[string][]map[string]interface{}, error)
+	// Transaction run fn with a store undoing its changes if fn fails
+	Transaction(fn func(IGraphStore) error) error
 }
@@ -24,14 +24,17 @@ package engine
 
 import (
 	"reflect"
+	"sort"
 	"strings"
 
+	log "github.com/sirupsen/logrus"
+
 	"github.com/yroffin/go-boot-sqllite/core/models"
 	"github.com/yroffin/go-boot-sqllite/core/winter"
 )
 
 func init() {
-	winter.Helper.Register("swagger", (&SwaggerService{}).New())
+	winter.Define("swagger", func() winter.IBean { return (&SwaggerService{}).New() })
 }
 
 // SwaggerService internal members
@@ -40,6 +43,8 @@ type SwaggerService struct {
 	*winter.Service
 	// swagger model
 	Swagger *models.SwaggerModel
+	// Host exposed in swagger model
+	Host string `@value:"swagger.host" @default:"localhost:3000"`
 }
 
 // ISwaggerService Test all package methods
@@ -51,6 +56,7 @@ type ISwaggerService interface {
 	BasePath(string) string
 	// swagger method
 	AddPaths(tags string, route string, method string, sumary string, description string, args map[string]interface{}, params map[string]interface{}, in []interface{}, out map[string]interface{})
+	AddTask(tags string, route string, task *Task)
 }
 
 // New constructor
@@ -64,7 +70,7 @@ func (p *SwaggerService) Init() error {
 	// root model
 	p.Swagger = &models.SwaggerModel{
 		Swagger:  "2.0",
-		Host:     "localhost:3000",
+		Host:     p.Host,
 		BasePath: "/",
 		Info: models.SwaggerInfo{
 			Description:    "Todo.",
@@ -94,6 +100,8 @@ func (p *SwaggerService) Init() error {
 
 // PostConstruct Init this API
 func (p *SwaggerService) PostConstruct(name string) error {
+	// Host is injected after Init
+	p.Swagger.Host = p.Host
 	return nil
 }
 
@@ -183,12 +191,21 @@ func (p *SwaggerService) AddPaths(tags string, route string, method string, summ
 		}
 		detail.Parameters = append(detail.Parameters, prm)
 	}
-	// Parameter query
-	for k, v := range query {
+	// Parameter query, a string value is its description
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		description, ok := query[k].(string)
+		if !ok {
+			description = reflect.TypeOf(query[k]).String()
+		}
 		prm := models.SwaggerMethodParamBody{
 			In:          "query",
 			Name:        k,
-			Description: reflect.TypeOf(v).String(),
+			Description: description,
 			Required:    false,
 			Type:        "string",
 		}
@@ -215,37 +232,179 @@ func (p *SwaggerService) AddPaths(tags string, route string, method string, summ
 	p.Swagger.Paths[route][met] = *detail
 }
 
-// AddDefinition method
-func (p *SwaggerService) getType(typ string) (string, string) {
-	switch typ {
+// AddTask document a task of the POST method of this route, its name is a
+// value of the task parameter and its input and output are definitions
+func (p *SwaggerService) AddTask(tags string, route string, task *Task) {
+	route = strings.Replace(route, ":id", "{id}", -1)
+	if _, ok := p.Swagger.Paths[route]["post"]; !ok {
+		p.AddPaths(tags, route, "POST", "Execute a task", "Execute a task", map[string]interface{}{}, map[string]interface{}{}, []interface{}{}, map[string]interface{}{})
+	}
+	detail := p.Swagger.Paths[route]["post"]
+	found := false
+	for index := range detail.Parameters {
+		if detail.Parameters[index].In == "query" && detail.Parameters[index].Name == "task" {
+			detail.Parameters[index].Enum = append(detail.Parameters[index].Enum, task.Name)
+			found = true
+		}
+	}
+	if !found {
+		detail.Parameters = append(detail.Parameters, models.SwaggerMethodParamBody{
+			In:          "query",
+			Name:        "task",
+			Description: "Task to execute",
+			Type:        "string",
+			Enum:        []string{task.Name},
+		})
+	}
+	var description = "task " + task.Name
+	if task.In != nil {
+		description = description + ", input " + p.AddDefinition(task.In.Elem().Name(), reflect.New(task.In.Elem()).Interface())
+	}
+	if task.Out != nil {
+		out := task.Out
+		for out.Kind() == reflect.Ptr {
+			out = out.Elem()
+		}
+		description = description + ", output " + p.AddDefinition(out.Name(), reflect.New(out).Interface())
+	}
+	if task.Async {
+		description = description + ", run as a job"
+	}
+	detail.Description = detail.Description + "\n" + description
+	p.Swagger.Paths[route]["post"] = detail
+}
+
+// getType swagger type and format of a go type
+func (p *SwaggerService) getType(typ reflect.Type) (string, string) {
+	switch typ.Name() {
 	case "string":
 		return "string", "string"
 	case "JSONTime":
 		return "string", "date-time"
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		return "boolean", ""
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer", typ.Kind().String()
+	case reflect.Float32, reflect.Float64:
+		return "number", typ.Kind().String()
+	case reflect.Map, reflect.Struct:
+		return "object", ""
+	case reflect.Slice, reflect.Array:
+		return "array", ""
 	default:
 		return "string", "string"
 	}
 }
 
-// AddDefinition method
+// AddDefinition method, properties are json fields with their @validate rules
 func (p *SwaggerService) AddDefinition(name string, ptr interface{}) string {
 	if _, ok := p.Swagger.Definitions[name]; ok {
 		return name
 	}
 
-	p.Swagger.Definitions[name] = models.SwaggerDefinitions{
+	definition := models.SwaggerDefinitions{
 		Type:       "Object",
 		Properties: make(map[string]models.SwaggerFormat),
 	}
+	var typ = reflect.TypeOf(ptr)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ != nil && typ.Kind() == reflect.Struct {
+		definition.Required = p.addProperties(typ, definition.Properties)
+	}
+	p.Swagger.Definitions[name] = definition
+	return name
+}
 
-	var fields = reflect.TypeOf(reflect.ValueOf(ptr))
-	for index := 0; index < fields.NumField(); index++ {
-		var fieldName = fields.Field(index).Name
-		var a, b = p.getType(fields.Field(index).Type.Name())
-		p.Swagger.Definitions[name].Properties[fieldName] = models.SwaggerFormat{
+// addProperties of the json fields of this struct, embedded structs included,
+// return required fields
+func (p *SwaggerService) addProperties(typ reflect.Type, properties map[string]models.SwaggerFormat) []string {
+	required := make([]string, 0)
+	for index := 0; index < typ.NumField(); index++ {
+		var field = typ.Field(index)
+		var fieldName = jsonName(field)
+		if len(field.PkgPath) > 0 || len(fieldName) == 0 {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			required = append(required, p.addProperties(field.Type, properties)...)
+			continue
+		}
+		var a, b = p.getType(field.Type)
+		format := models.SwaggerFormat{
 			Type:   a,
 			Format: b,
 		}
+		rules, err := ParseRules(field.Tag.Get("@validate"))
+		if err != nil {
+			log.WithFields(log.Fields{
+				"field": typ.Name() + "." + field.Name,
+				"error": err,
+			}).Warn("Malformed @validate tag")
+		}
+		for _, rule := range rules {
+			if len(rule.Key) == 0 {
+				if rule.Name == "required" {
+					required = append(required, fieldName)
+				}
+				p.addRule(&format, rule)
+				continue
+			}
+			// rules of map keys
+			if format.Properties == nil {
+				format.Properties = make(map[string]models.SwaggerFormat)
+			}
+			property := format.Properties[rule.Key]
+			if rule.Name == "required" {
+				format.Required = append(format.Required, rule.Key)
+			}
+			p.addRule(&property, rule)
+			format.Properties[rule.Key] = property
+		}
+		properties[fieldName] = format
+	}
+	return required
+}
+
+// addRule describe a validation rule, min and max of an untyped map key
+// are numeric bounds
+func (p *SwaggerService) addRule(format *models.SwaggerFormat, rule Rule) {
+	switch rule.Name {
+	case "regexp":
+		format.Pattern = rule.Arg
+	case "enum":
+		format.Enum = strings.Split(rule.Arg, "|")
+	case "min", "max":
+		bound := rule.bound
+		size := int(bound)
+		switch format.Type {
+		case "string":
+			if rule.Name == "min" {
+				format.MinLength = &size
+			} else {
+				format.MaxLength = &size
+			}
+		case "array":
+			if rule.Name == "min" {
+				format.MinItems = &size
+			} else {
+				format.MaxItems = &size
+			}
+		case "object":
+			if rule.Name == "min" {
+				format.MinProperties = &size
+			} else {
+				format.MaxProperties = &size
+			}
+		default:
+			if rule.Name == "min" {
+				format.Minimum = &bound
+			} else {
+				format.Maximum = &bound
+			}
+		}
 	}
-	return name
 }
@@ -0,0 +1,138 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Task a named task of an API, run by POST ?task=name
+//
+//	Rebuild func(ctx context.Context, input *RebuildInput) (*RebuildOutput, error) `@task:"rebuild"`
+//	Reset   func(id string) error                                                  `@task:"reset" @async:"true"`
+//	Move    func(ctx context.Context, id string, input *MoveInput) error            `@task:"move" @unit:"true"`
+//
+// Its function takes an optional context, an id for tasks on a single
+// resource, and an optional input struct decoded from the body and
+// validated; it returns an optional output and an error
+type Task struct {
+	// Name of this task
+	Name string
+	// ByID true for tasks on a single resource
+	ByID bool
+	// Async true if this task is run as a job, see IJobs
+	Async bool
+	// Unit true if this task is run in a unit of work, see UnitOf
+	Unit bool
+	// In input type, nil without input
+	In reflect.Type
+	// Out output type, nil without output
+	Out reflect.Type
+	// fn task function
+	fn reflect.Value
+	// ctx true if fn takes a context
+	ctx bool
+}
+
+// NewTask check a task function
+func NewTask(name string, fn interface{}, async bool) (*Task, error) {
+	value := reflect.ValueOf(fn)
+	if value.Kind() != reflect.Func || value.IsNil() {
+		return nil, errors.New("task " + name + " is not a function")
+	}
+	typ := value.Type()
+	task := &Task{Name: name, Async: async, fn: value}
+	index := 0
+	if index < typ.NumIn() && typ.In(index) == contextType {
+		task.ctx = true
+		index++
+	}
+	if index < typ.NumIn() && typ.In(index).Kind() == reflect.String {
+		task.ByID = true
+		index++
+	}
+	if index < typ.NumIn() {
+		if typ.In(index).Kind() != reflect.Ptr || typ.In(index).Elem().Kind() != reflect.Struct {
+			return nil, errors.New("task " + name + " input must be a struct pointer")
+		}
+		task.In = typ.In(index)
+		index++
+	}
+	if index < typ.NumIn() {
+		return nil, errors.New("task " + name + " has too many arguments")
+	}
+	switch {
+	case typ.NumOut() == 1 && typ.Out(0) == errorType:
+	case typ.NumOut() == 2 && typ.Out(1) == errorType:
+		task.Out = typ.Out(0)
+	default:
+		return nil, errors.New("task " + name + " must return an optional output and an error")
+	}
+	return task, nil
+}
+
+// Input decode and validate the input of this task, nil without input
+func (t *Task) Input(entity string, body []byte) (interface{}, error) {
+	if t.In == nil {
+		return nil, nil
+	}
+	input := reflect.New(t.In.Elem()).Interface()
+	if len(strings.TrimSpace(string(body))) > 0 {
+		if err := json.Unmarshal(body, input); err != nil {
+			return nil, invalid(entity, "malformed input of task "+t.Name, err)
+		}
+	}
+	if err := ValidateValue(entity, input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+// Run this task with a decoded input
+func (t *Task) Run(ctx context.Context, id string, input interface{}) (interface{}, error) {
+	args := make([]reflect.Value, 0, 3)
+	if t.ctx {
+		args = append(args, reflect.ValueOf(ctx))
+	}
+	if t.ByID {
+		args = append(args, reflect.ValueOf(id))
+	}
+	if t.In != nil {
+		args = append(args, reflect.ValueOf(input))
+	}
+	results := t.fn.Call(args)
+	err, _ := results[len(results)-1].Interface().(error)
+	if t.Out == nil {
+		return nil, err
+	}
+	return results[0].Interface(), err
+}
@@ -0,0 +1,135 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rebuildInput input of the rebuild task
+type rebuildInput struct {
+	Depth int `json:"depth" @validate:"min=1"`
+}
+
+// rebuildOutput output of the rebuild task
+type rebuildOutput struct {
+	ID    string `json:"id"`
+	Depth int    `json:"depth"`
+}
+
+// rebuild a task on a single resource
+func rebuild(ctx context.Context, id string, input *rebuildInput) (*rebuildOutput, error) {
+	return &rebuildOutput{ID: id, Depth: input.Depth}, nil
+}
+
+func TestNewTask(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   interface{}
+		err  bool
+		byID bool
+		in   reflect.Type
+		out  reflect.Type
+	}{
+		{"full", rebuild, false, true, reflect.TypeOf(&rebuildInput{}), reflect.TypeOf(&rebuildOutput{})},
+		{"bare", func() error { return nil }, false, false, nil, nil},
+		{"input", func(ctx context.Context, input *rebuildInput) error { return nil }, false, false, reflect.TypeOf(&rebuildInput{}), nil},
+		{"not a function", "rebuild", true, false, nil, nil},
+		{"no error", func() {}, true, false, nil, nil},
+		{"struct input", func(input rebuildInput) error { return nil }, true, false, nil, nil},
+		{"too many arguments", func(id string, input *rebuildInput, other int) error { return nil }, true, false, nil, nil},
+	}
+	for _, test := range tests {
+		task, err := NewTask(test.name, test.fn, false)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: accepted", test.name)
+			}
+			continue
+		}
+		if err != nil || task.ByID != test.byID || task.In != test.in || task.Out != test.out {
+			t.Errorf("%s: got %+v %v", test.name, task, err)
+		}
+	}
+}
+
+func TestTaskDispatch(t *testing.T) {
+	store := memoryStore(t)
+	defer store.PreDestroy("store")
+	if err := store.table("JobBean"); err != nil {
+		t.Fatal(err)
+	}
+	jobs := (&Jobs{}).New().(*Jobs)
+	jobs.Store, jobs.Workers, jobs.Queue = store, 1, 1
+	jobs.DrainTimeout, jobs.ProgressStep, jobs.ProgressInterval = time.Second, 10, time.Hour
+	if err := jobs.PostConstruct("jobs"); err != nil {
+		t.Fatal(err)
+	}
+	defer jobs.PreDestroy("jobs")
+	api := nodeAPI(store)
+	api.Jobs = jobs
+	if _, err := api.RegisterTask("rebuild", rebuild, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.RegisterTask("reset", func(id string) error { return nil }, true); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		id     string
+		query  string
+		body   string
+		status int
+	}{
+		{"sync", "sensor-1", "?task=rebuild", `{"depth":2}`, http.StatusOK},
+		{"invalid input", "sensor-1", "?task=rebuild", `{"depth":0}`, http.StatusUnprocessableEntity},
+		{"malformed input", "sensor-1", "?task=rebuild", `{`, http.StatusUnprocessableEntity},
+		{"unknown task", "sensor-1", "?task=missing", `{}`, http.StatusNotFound},
+		{"task by id on all resources", "*", "?task=rebuild", `{"depth":2}`, http.StatusNotFound},
+		{"async", "sensor-1", "?task=reset", ``, http.StatusAccepted},
+	}
+	for _, test := range tests {
+		recorder := call(api.HandlerStaticPostByID(), "POST", test.id, test.query, test.body, "application/json")
+		if recorder.Code != test.status {
+			t.Errorf("%s: got %d %s", test.name, recorder.Code, recorder.Body)
+			continue
+		}
+		switch test.name {
+		case "sync":
+			output := &rebuildOutput{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), output); err != nil || *output != (rebuildOutput{ID: "sensor-1", Depth: 2}) {
+				t.Errorf("%s: got %s", test.name, recorder.Body)
+			}
+		case "async":
+			if location := recorder.Header().Get("Location"); !strings.HasPrefix(location, JobsPath+"/") {
+				t.Errorf("%s: located at %s", test.name, location)
+			}
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"encoding/json"
+
+	"github.com/yroffin/go-boot-sqllite/core/models"
+)
+
+const (
+	// VisitPath an entity is expanded once per path, the default
+	VisitPath = "path"
+	// VisitGlobal an entity is expanded once per traversal
+	VisitGlobal = "global"
+)
+
+// Hop filters of links followed at one depth, empty filters accept any link
+type Hop struct {
+	// Links names of followed links, the @href of their declaration
+	Links []string `json:"links,omitempty"`
+	// Targets entity names of targets
+	Targets []string `json:"targets,omitempty"`
+	// Where values of extended properties of followed links
+	Where map[string]interface{} `json:"where,omitempty"`
+}
+
+// Traversal of links from a root entity, see ILinkBusiness.Traverse
+//
+//	{"depth": 3, "hops": [{"targets": ["NodeBean"], "where": {"kind": "parent"}}]}
+type Traversal struct {
+	// From entity name of the root
+	From string `json:"-"`
+	// ID of the root
+	ID string `json:"-"`
+	// Hops filters by depth, the last one applies to deeper hops
+	Hops []Hop `json:"hops,omitempty"`
+	// Depth count of hops, len(Hops) or 1 if 0
+	Depth int `json:"depth,omitempty" @validate:"min=0"`
+	// Visit VisitPath or VisitGlobal
+	Visit string `json:"visit,omitempty" @validate:"enum=path|global"`
+}
+
+// Branch an entity reached by a traversal, the tree of its links
+type Branch struct {
+	Entity string `json:"entity"`
+	ID     string `json:"id"`
+	// Edge followed to reach this entity, nil at the root
+	Edge models.IEdgeBean `json:"edge,omitempty"`
+	// Visited true if this entity was already reached, it is not expanded
+	Visited  bool      `json:"visited,omitempty"`
+	Children []*Branch `json:"children,omitempty"`
+	// parent branch, nil at the root
+	parent *Branch
+}
+
+// depth count of hops of this traversal
+func (t *Traversal) depth() int {
+	if t.Depth > 0 {
+		return t.Depth
+	}
+	if len(t.Hops) > 0 {
+		return len(t.Hops)
+	}
+	return 1
+}
+
+// hop filters at this depth
+func (t *Traversal) hop(depth int) Hop {
+	if len(t.Hops) == 0 {
+		return Hop{}
+	}
+	if depth < len(t.Hops) {
+		return t.Hops[depth]
+	}
+	return t.Hops[len(t.Hops)-1]
+}
+
+// accept true if this link passes the filters of this hop, extended
+// properties are compared as json
+func (h Hop) accept(edge models.IEdgeBean) bool {
+	if len(h.Links) > 0 && !contains(h.Links, edge.GetName()) {
+		return false
+	}
+	if len(h.Targets) > 0 && !contains(h.Targets, edge.GetTarget()) {
+		return false
+	}
+	extend := edge.GetExtend()
+	for key, value := range h.Where {
+		expected, _ := json.Marshal(value)
+		actual, _ := json.Marshal(extend[key])
+		if string(expected) != string(actual) {
+			return false
+		}
+	}
+	return true
+}
+
+// contains true if value is one of values
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// node of this branch in the graph
+func (b *Branch) node() string {
+	return "/" + b.Entity + "/" + b.ID
+}
+
+// onPath true if this node is an ancestor of this branch, or itself
+func (b *Branch) onPath(node string) bool {
+	for branch := b; branch != nil; branch = branch.parent {
+		if branch.node() == node {
+			return true
+		}
+	}
+	return false
+}
+
+// Paths flat list of the paths from the root to each leaf, as their links
+func (b *Branch) Paths() [][]models.IEdgeBean {
+	paths := make([][]models.IEdgeBean, 0)
+	if len(b.Children) == 0 {
+		if b.parent == nil {
+			return paths
+		}
+		path := make([]models.IEdgeBean, 0)
+		for branch := b; branch.parent != nil; branch = branch.parent {
+			path = append([]models.IEdgeBean{branch.Edge}, path...)
+		}
+		return append(paths, path)
+	}
+	for _, child := range b.Children {
+		paths = append(paths, child.Paths()...)
+	}
+	return paths
+}
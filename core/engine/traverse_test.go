@@ -0,0 +1,58 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"testing"
+
+	"github.com/yroffin/go-boot-sqllite/core/models"
+)
+
+func TestHopAccept(t *testing.T) {
+	edge := (&models.EdgeBean{}).New("NodeBean", "1", "NodeBean", "2", LinkHREF)
+	edge.SetName("nodes")
+	edge.Extend(map[string]interface{}{"kind": "parent", "level": 1})
+	legacy := (&models.EdgeBean{}).New("NodeBean", "1", "NodeBean", "3", LinkHREF)
+	tests := []struct {
+		name   string
+		hop    Hop
+		edge   models.IEdgeBean
+		accept bool
+	}{
+		{"no filter", Hop{}, edge, true},
+		{"link", Hop{Links: []string{"other", "nodes"}}, edge, true},
+		{"other link", Hop{Links: []string{"other"}}, edge, false},
+		{"predicate is no link name", Hop{Links: []string{LinkHREF}}, edge, false},
+		{"unnamed link", Hop{Links: []string{"nodes"}}, legacy, false},
+		{"target", Hop{Targets: []string{"NodeBean"}}, edge, true},
+		{"other target", Hop{Targets: []string{"JobBean"}}, edge, false},
+		{"where", Hop{Where: map[string]interface{}{"kind": "parent", "level": 1.0}}, edge, true},
+		{"other where", Hop{Where: map[string]interface{}{"kind": "child"}}, edge, false},
+		{"missing where", Hop{Where: map[string]interface{}{"room": "kitchen"}}, edge, false},
+	}
+	for _, test := range tests {
+		if accept := test.hop.accept(test.edge); accept != test.accept {
+			t.Errorf("%s: got %v", test.name, accept)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"context"
+	"sync"
+
+	"github.com/yroffin/go-boot-sqllite/core/winter"
+)
+
+const (
+	// ContextKey request attribute of the context of a request, see API.Context
+	ContextKey = "engine.context"
+)
+
+// Unit a unit of work grouping entity and link writes, opened by
+// ICrudBusiness.Work: entities are written in a single sql transaction,
+// links are undone if the unit fails, events are published once committed.
+// Opened through an intercepted bean, its Crud and Links go through the
+// same interceptors
+//
+//	err := p.SQLCrudBusiness.Work(ctx, func(ctx context.Context) error {
+//		unit, _ := engine.UnitOf(ctx)
+//		if _, err := unit.Crud.Create(node); err != nil {
+//			return err
+//		}
+//		_, err := unit.Links.CreateLink(edge)
+//		return err
+//	})
+type Unit struct {
+	// Crud entities of this unit, bound to its sql transaction
+	Crud ICrudBusiness
+	// Links of this unit, nil without graph store
+	Links ILinkBusiness
+}
+
+// unitKey key of the unit of work in a context
+type unitKey struct{}
+
+// WithUnit a context carrying this unit of work
+func WithUnit(ctx context.Context, unit *Unit) context.Context {
+	return context.WithValue(ctx, unitKey{}, unit)
+}
+
+// UnitOf the unit of work of this context, if any
+func UnitOf(ctx context.Context) (*Unit, bool) {
+	unit, ok := ctx.Value(unitKey{}).(*Unit)
+	return unit, ok
+}
+
+// deferred event bus, events are kept until flushed
+type deferred struct {
+	winter.IEventBus
+	events []interface{}
+	mutex  sync.Mutex
+}
+
+// Publish keep this event
+func (p *deferred) Publish(event interface{}) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.events = append(p.events, event)
+}
+
+// flush publish all kept events
+func (p *deferred) flush() {
+	p.mutex.Lock()
+	events := p.events
+	p.events = nil
+	p.mutex.Unlock()
+	for _, event := range events {
+		p.IEventBus.Publish(event)
+	}
+}
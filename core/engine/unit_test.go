@@ -0,0 +1,169 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/cayley/quad"
+
+	"github.com/yroffin/go-boot-sqllite/core/models"
+	"github.com/yroffin/go-boot-sqllite/core/winter"
+)
+
+// memoryGraph an in memory graph store
+func memoryGraph(t *testing.T) *Graph {
+	handle, err := cayley.NewMemoryGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph := (&Graph{}).New().(*Graph)
+	graph.store = handle
+	return graph
+}
+
+// linked true if this link instance is stored
+func linked(graph *Graph, edge models.IEdgeBean) bool {
+	return len(graph.lookup(quad.Predicate, graph.predicate(edge))) > 0
+}
+
+// recorder interceptor keeping the bean and method of each call
+type recorder struct {
+	calls []string
+	mutex sync.Mutex
+}
+
+// Intercept record this call
+func (r *recorder) Intercept(invocation *winter.Invocation) []interface{} {
+	r.mutex.Lock()
+	r.calls = append(r.calls, invocation.Bean+"."+invocation.Method)
+	r.mutex.Unlock()
+	return invocation.Proceed()
+}
+
+// unitBeans sql and graph business beans over in memory stores, proxied
+// by these aspects
+func unitBeans(t *testing.T, aspects winter.IAspects) (*Store, *Graph, ICrudBusiness, ILinkBusiness) {
+	store := memoryStore(t)
+	graph := memoryGraph(t)
+	events := (&winter.EventBus{}).New()
+	links := aspects.Proxy("graph-crud-business", reflect.TypeOf((*ILinkBusiness)(nil)).Elem(), &GraphCrudBusiness{Store: graph, Events: events}).(ILinkBusiness)
+	crud := aspects.Proxy("sql-crud-business", reflect.TypeOf((*ICrudBusiness)(nil)).Elem(), &SqlCrudBusiness{Store: store, Events: events, Graph: links}).(ICrudBusiness)
+	return store, graph, crud, links
+}
+
+func TestUnitInterceptors(t *testing.T) {
+	aspects := (&winter.Aspects{}).New()
+	calls := &recorder{}
+	aspects.Intercept(winter.Pointcut{}, calls)
+	store, _, crud, links := unitBeans(t, aspects)
+	defer store.PreDestroy("store")
+	api := &API{SQLCrudBusiness: crud, GraphBusiness: links, Factory: func() models.IPersistent { return (&models.NodeBean{}).New() }}
+	bean := node("temp", "sensor", nil)
+	err := crud.Work(context.Background(), func(ctx context.Context) error {
+		unit, _ := UnitOf(ctx)
+		if _, err := unit.Crud.Create(bean); err != nil {
+			return err
+		}
+		if _, err := unit.Links.CreateLink((&models.EdgeBean{}).New("NodeBean", bean.ID, "NodeBean", bean.ID, LinkHREF)); err != nil {
+			return err
+		}
+		_, err := api.within(ctx).SQLCrudBusiness.Get(node("", "", nil))
+		if KindOf(err) != NotFound {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"sql-crud-business.Work",
+		"graph-crud-business.Transaction",
+		"sql-crud-business.Create",
+		"graph-crud-business.CreateLink",
+		"sql-crud-business.Get",
+	}
+	if !reflect.DeepEqual(calls.calls, expected) {
+		t.Errorf("got %v, expected %v", calls.calls, expected)
+	}
+}
+
+func TestUnitUndo(t *testing.T) {
+	failure := errors.New("failure")
+	tests := []struct {
+		name string
+		// fn fails after its writes, or an entity write fails it
+		write bool
+	}{
+		{"failed function", false},
+		{"failed entity write", true},
+	}
+	for _, test := range tests {
+		store, graph, crud, _ := unitBeans(t, (&winter.Aspects{}).New())
+		existing := node("existing", "", nil)
+		if err := store.Create(existing); err != nil {
+			t.Fatal(err)
+		}
+		kept := (&models.EdgeBean{}).New("NodeBean", existing.ID, "NodeBean", existing.ID, LinkHREF)
+		if err := graph.CreateLink(kept); err != nil {
+			t.Fatal(err)
+		}
+		created := (&models.EdgeBean{}).New("NodeBean", existing.ID, "NodeBean", existing.ID, LinkHREF)
+		bean := node("new", "", nil)
+		err := crud.Work(context.Background(), func(ctx context.Context) error {
+			unit, _ := UnitOf(ctx)
+			if _, err := unit.Links.CreateLink(created); err != nil {
+				return err
+			}
+			if _, err := unit.Links.DeleteLink(kept); err != nil {
+				return err
+			}
+			if _, err := unit.Crud.Create(bean); err != nil {
+				return err
+			}
+			if test.write {
+				missing := node("missing", "", nil)
+				missing.SetID("missing")
+				_, err := unit.Crud.Update(missing)
+				return err
+			}
+			return failure
+		})
+		if err == nil {
+			t.Errorf("%s: unit committed", test.name)
+		}
+		if !linked(graph, kept) || linked(graph, created) {
+			t.Errorf("%s: links not undone", test.name)
+		}
+		if err := store.Get(bean.ID, node("", "", nil)); KindOf(err) != NotFound {
+			t.Errorf("%s: entity not rolled back %v", test.name, err)
+		}
+		store.PreDestroy("store")
+	}
+}
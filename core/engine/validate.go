@@ -0,0 +1,355 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/yroffin/go-boot-sqllite/core/models"
+)
+
+// Violation a failing field of a validated entity
+type Violation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Rule a validation rule of a @validate tag, Key scopes it to a key of a
+// map field
+//
+//	Type     string                 `json:"type" @validate:"required; enum=sensor|actuator"`
+//	Extended map[string]interface{} `json:"extended" @validate:"room:required; level:min=0; level:max=10"`
+//
+// Rules are required, min, max (value of numbers, length of strings, size of
+// arrays and maps), regexp and enum (values separated by |); rules other than
+// required only apply to present values. Rules are separated by ; so that a
+// regexp may contain spaces, \; is a literal ;
+type Rule struct {
+	Key     string
+	Name    string
+	Arg     string
+	bound   float64
+	pattern *regexp.Regexp
+}
+
+// field rules of a struct field
+type field struct {
+	index []int
+	name  string
+	rules []Rule
+}
+
+var (
+	// fields by struct type, tags are parsed once
+	fields = sync.Map{}
+)
+
+// ParseRules parse a @validate tag, rules are separated by ;
+func ParseRules(tag string) ([]Rule, error) {
+	rules := make([]Rule, 0)
+	for _, text := range splitRules(tag) {
+		rule := Rule{}
+		// a key prefix ends before any argument
+		if colon := strings.Index(text, ":"); colon > 0 && (strings.Index(text, "=") < 0 || colon < strings.Index(text, "=")) {
+			rule.Key, text = text[:colon], text[colon+1:]
+		}
+		if equal := strings.Index(text, "="); equal >= 0 {
+			rule.Name, rule.Arg = text[:equal], text[equal+1:]
+		} else {
+			rule.Name = text
+		}
+		var err error
+		switch rule.Name {
+		case "required":
+		case "enum":
+			if len(rule.Arg) == 0 {
+				return nil, errors.New("enum without values")
+			}
+		case "min", "max":
+			if rule.bound, err = strconv.ParseFloat(rule.Arg, 64); err != nil {
+				return nil, fmt.Errorf("%s needs a number: %v", rule.Name, err)
+			}
+		case "regexp":
+			if rule.pattern, err = regexp.Compile(rule.Arg); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, errors.New("unknown rule " + rule.Name)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// splitRules split a tag on ; but not on \;, surrounding spaces and empty
+// rules are dropped
+func splitRules(tag string) []string {
+	texts := make([]string, 0)
+	var text strings.Builder
+	flush := func() {
+		if trimmed := strings.TrimSpace(text.String()); len(trimmed) > 0 {
+			texts = append(texts, trimmed)
+		}
+		text.Reset()
+	}
+	for index := 0; index < len(tag); index++ {
+		switch {
+		case tag[index] == '\\' && index+1 < len(tag) && tag[index+1] == ';':
+			text.WriteByte(';')
+			index++
+		case tag[index] == ';':
+			flush()
+		default:
+			text.WriteByte(tag[index])
+		}
+	}
+	flush()
+	return texts
+}
+
+// String the rule as written in its tag
+func (r Rule) String() string {
+	if len(r.Arg) > 0 {
+		return r.Name + "=" + r.Arg
+	}
+	return r.Name
+}
+
+// jsonName name of this field in json, empty if not marshalled
+func jsonName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	if len(name) == 0 {
+		return f.Name
+	}
+	return name
+}
+
+// rulesOf the validated fields of this struct type, embedded and nested
+// structs included, pointers are not followed
+func rulesOf(typ reflect.Type) ([]field, error) {
+	if cached, ok := fields.Load(typ); ok {
+		return cached.([]field), nil
+	}
+	result := make([]field, 0)
+	for index := 0; index < typ.NumField(); index++ {
+		f := typ.Field(index)
+		if len(f.PkgPath) > 0 {
+			continue
+		}
+		name := jsonName(f)
+		if len(name) == 0 {
+			continue
+		}
+		if tag, ok := f.Tag.Lookup("@validate"); ok {
+			rules, err := ParseRules(tag)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", typ.Name(), f.Name, err)
+			}
+			result = append(result, field{index: []int{index}, name: name, rules: rules})
+		}
+		if f.Type.Kind() != reflect.Struct {
+			continue
+		}
+		nested, err := rulesOf(f.Type)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nested {
+			if !f.Anonymous {
+				n.name = name + "." + n.name
+			}
+			n.index = append([]int{index}, n.index...)
+			result = append(result, n)
+		}
+	}
+	fields.Store(typ, result)
+	return result, nil
+}
+
+// Validate check the @validate rules of this entity, then its own
+// Validate() hook; all violations are returned in a Validation error
+func Validate(entity models.IPersistent) error {
+	return ValidateValue(entity.GetEntityName(), entity)
+}
+
+// ValidateValue check the @validate rules and the Validate() hook of any
+// struct, like task inputs; errors name this entity
+func ValidateValue(entity string, data interface{}) error {
+	violations := make([]Violation, 0)
+	value := reflect.ValueOf(data)
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() == reflect.Struct {
+		rules, err := rulesOf(value.Type())
+		if err != nil {
+			return internal(entity, "malformed @validate tag", err)
+		}
+		for _, f := range rules {
+			violations = append(violations, f.check(value)...)
+		}
+	}
+	if validated, ok := data.(models.IValidated); ok {
+		if err := validated.Validate(); err != nil {
+			var typed *Error
+			if errors.As(err, &typed) && len(typed.Violations) > 0 {
+				violations = append(violations, typed.Violations...)
+			} else {
+				violations = append(violations, Violation{Rule: "Validate", Message: err.Error()})
+			}
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return violated(entity, violations)
+}
+
+// violated error listing these violations
+func violated(entity string, violations []Violation) error {
+	names := make([]string, 0, len(violations))
+	messages := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, violation := range violations {
+		if len(violation.Field) == 0 {
+			messages = append(messages, violation.Message)
+		} else if !seen[violation.Field] {
+			seen[violation.Field] = true
+			names = append(names, violation.Field)
+		}
+	}
+	sort.Strings(names)
+	if len(names) > 0 {
+		messages = append([]string{"invalid fields " + strings.Join(names, ", ")}, messages...)
+	}
+	message := strings.Join(messages, ", ")
+	return &Error{Kind: Validation, Entity: entity, Message: message, Violations: violations}
+}
+
+// check the rules of this field on a struct value
+func (f field) check(value reflect.Value) []Violation {
+	violations := make([]Violation, 0)
+	value = value.FieldByIndex(f.index)
+	for _, rule := range f.rules {
+		name := f.name
+		target := value
+		if len(rule.Key) > 0 {
+			name = f.name + "." + rule.Key
+			target = reflect.Value{}
+			if value.Kind() == reflect.Map && !value.IsNil() {
+				target = value.MapIndex(reflect.ValueOf(rule.Key))
+			}
+		}
+		if message := rule.check(target); len(message) > 0 {
+			violations = append(violations, Violation{Field: name, Rule: rule.String(), Message: message})
+		}
+	}
+	return violations
+}
+
+// present false for invalid, nil and empty values, numbers and booleans are
+// always present
+func present(value reflect.Value) bool {
+	for value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return false
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Invalid:
+		return false
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len() > 0
+	}
+	return true
+}
+
+// check this rule, return a message if it is violated
+func (r Rule) check(value reflect.Value) string {
+	if !present(value) {
+		if r.Name == "required" {
+			return "is required"
+		}
+		return ""
+	}
+	for value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	switch r.Name {
+	case "min", "max":
+		var size float64
+		var what = "value"
+		switch value.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			size = float64(value.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			size = float64(value.Uint())
+		case reflect.Float32, reflect.Float64:
+			size = value.Float()
+		case reflect.String:
+			size, what = float64(utf8.RuneCountInString(value.String())), "length"
+		case reflect.Slice, reflect.Map, reflect.Array:
+			size, what = float64(value.Len()), "size"
+		default:
+			return "must be a number, a string, an array or an object"
+		}
+		if r.Name == "min" && size < r.bound {
+			return what + " must be at least " + r.Arg
+		}
+		if r.Name == "max" && size > r.bound {
+			return what + " must be at most " + r.Arg
+		}
+	case "regexp":
+		if value.Kind() != reflect.String {
+			return "must be a string"
+		}
+		if !r.pattern.MatchString(value.String()) {
+			return "must match " + r.Arg
+		}
+	case "enum":
+		text := fmt.Sprint(value.Interface())
+		for _, allowed := range strings.Split(r.Arg, "|") {
+			if text == allowed {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Replace(r.Arg, "|", ", ", -1)
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package engine
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/yroffin/go-boot-sqllite/core/models"
+)
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		tag   string
+		rules []string
+		err   bool
+	}{
+		{``, []string{}, false},
+		{`required`, []string{"required"}, false},
+		{` required ; enum=a|b ;`, []string{"required", "enum=a|b"}, false},
+		{`room:required; level:min=0`, []string{"room:required", "level:min=0"}, false},
+		{`regexp=^[a-z]+ [a-z]+$`, []string{"regexp=^[a-z]+ [a-z]+$"}, false},
+		{`regexp=^a\;b:c$; max=3`, []string{"regexp=^a;b:c$", "max=3"}, false},
+		{`required enum=a|b`, nil, true},
+		{`min=x`, nil, true},
+		{`enum=`, nil, true},
+		{`regexp=(`, nil, true},
+	}
+	for _, test := range tests {
+		rules, err := ParseRules(test.tag)
+		if test.err != (err != nil) {
+			t.Errorf("%s: got %v", test.tag, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		texts := make([]string, 0)
+		for _, rule := range rules {
+			if len(rule.Key) > 0 {
+				texts = append(texts, rule.Key+":"+rule.String())
+			} else {
+				texts = append(texts, rule.String())
+			}
+		}
+		if !reflect.DeepEqual(texts, test.rules) {
+			t.Errorf("%s: got %q, expected %q", test.tag, texts, test.rules)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	long := strings.Repeat("x", 256)
+	tests := []struct {
+		name   string
+		entity models.IPersistent
+		fields []string
+	}{
+		{"valid node", node("temp", "sensor", nil), []string{}},
+		// nodes stored before rules existed stay valid
+		{"free node", node("", long, nil), []string{}},
+		{"valid edge", (&models.EdgeBean{}).New("NodeBean", "1", "NodeBean", "2", "parent"), []string{}},
+		{"dangling edge", (&models.EdgeBean{}).New("NodeBean", "1", "", "", "parent"), []string{"target", "targetId"}},
+		{"running job", &models.JobBean{Task: "repair", Status: JobRunning, Progress: 50}, []string{}},
+		{"unknown job", &models.JobBean{Task: "repair", Status: "lost", Progress: 101}, []string{"progress", "status"}},
+	}
+	for _, test := range tests {
+		fields := make([]string, 0)
+		if err := Validate(test.entity); err != nil {
+			typed, ok := err.(*Error)
+			if !ok || typed.Kind != Validation {
+				t.Errorf("%s: got %v", test.name, err)
+				continue
+			}
+			for _, violation := range typed.Violations {
+				fields = append(fields, violation.Field)
+			}
+		}
+		sort.Strings(fields)
+		if !reflect.DeepEqual(fields, test.fields) {
+			t.Errorf("%s: got %v, expected %v", test.name, fields, test.fields)
+		}
+	}
+}
@@ -34,22 +34,22 @@ type EdgeBean struct {
 	ID string `json:"id"`
 	// Timestamp
 	Timestamp JSONTime `json:"timestamp"`
-	// Name
+	// Name of the link declaration, its @href
 	Name string `json:"name"`
 	// Type
 	Type string `json:"type"`
 	// Extended internal store
 	Extended map[string]interface{} `json:"extended"`
 	// Source
-	Source string `json:"source"`
+	Source string `json:"source" @validate:"required"`
 	// SourceID
-	SourceID string `json:"sourceId"`
+	SourceID string `json:"sourceId" @validate:"required"`
 	// Target
-	Target string `json:"target"`
+	Target string `json:"target" @validate:"required"`
 	// TargetID
-	TargetID string `json:"targetId"`
+	TargetID string `json:"targetId" @validate:"required"`
 	// Link
-	Link string `json:"link"`
+	Link string `json:"link" @validate:"required"`
 	// Instance
 	Instance string `json:"instance"`
 }
@@ -67,6 +67,8 @@ type IEdgeBean interface {
 	GetTarget() string
 	GetTargetID() string
 	GetLink() string
+	GetName() string
+	SetName(string)
 	SetInstance(string)
 	GetInstance() string
 }
@@ -127,6 +129,16 @@ func (p *EdgeBean) GetLink() string {
 	return p.Link
 }
 
+// GetName get the name of the link declaration of this edge
+func (p *EdgeBean) GetName() string {
+	return p.Name
+}
+
+// SetName set the name of the link declaration of this edge
+func (p *EdgeBean) SetName(name string) {
+	p.Name = name
+}
+
 // GetEntityName get set name
 func (p *EdgeBean) GetEntityName() string {
 	return "Edge"
@@ -208,9 +220,13 @@ func (p *EdgeBean) GetTimestamp() JSONTime {
 	return p.Timestamp
 }
 
-// Copy retrieve ID
+// Copy retrieve ID, extended vars are not shared
 func (p *EdgeBean) Copy() IPersistent {
 	clone := *p
+	clone.Extended = make(map[string]interface{})
+	for k, v := range p.Extended {
+		clone.Extended[k] = v
+	}
 	return &clone
 }
 
@@ -0,0 +1,113 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package models
+
+// JobBean a persisted asynchronous task execution
+type JobBean struct {
+	// Id
+	ID string `json:"id"`
+	// Timestamp of the last change
+	Timestamp JSONTime `json:"timestamp"`
+	// Task name
+	Task string `json:"task" @validate:"required"`
+	// Status pending, running, done, failed or canceled
+	Status string `json:"status" @validate:"required; enum=pending|running|done|failed|canceled"`
+	// Progress percent and message reported by the task
+	Progress int    `json:"progress" @validate:"min=0; max=100"`
+	Message  string `json:"message,omitempty"`
+	// Result of a done job
+	Result interface{} `json:"result,omitempty"`
+	// Error problem details of a failed job
+	Error interface{} `json:"error,omitempty"`
+	// Created and Finished dates
+	Created  JSONTime  `json:"created"`
+	Finished *JSONTime `json:"finished,omitempty"`
+}
+
+// New constructor
+func (p *JobBean) New() IPersistent {
+	bean := JobBean{}
+	return &bean
+}
+
+// GetEntityName get set name
+func (p *JobBean) GetEntityName() string {
+	return "JobBean"
+}
+
+// GetID retrieve ID
+func (p *JobBean) GetID() string {
+	return p.ID
+}
+
+// SetID retrieve ID
+func (p *JobBean) SetID(ID string) {
+	p.ID = ID
+}
+
+// SetTimestamp set timestamp
+func (p *JobBean) SetTimestamp(stamp JSONTime) {
+	p.Timestamp = stamp
+}
+
+// GetTimestamp get timestamp
+func (p *JobBean) GetTimestamp() JSONTime {
+	return p.Timestamp
+}
+
+// Extend jobs have no extended vars
+func (p *JobBean) Extend(e map[string]interface{}) {
+}
+
+// GetExtend jobs have no extended vars
+func (p *JobBean) GetExtend() map[string]interface{} {
+	return nil
+}
+
+// Copy this job
+func (p *JobBean) Copy() IPersistent {
+	clone := *p
+	return &clone
+}
+
+// JobBeans simple bean model
+type JobBeans struct {
+	// Collection
+	Collection []IPersistent `json:"collections"`
+}
+
+// New constructor
+func (p *JobBeans) New() IPersistents {
+	bean := JobBeans{Collection: make([]IPersistent, 0)}
+	return &bean
+}
+
+// Add new bean
+func (p *JobBeans) Add(bean IPersistent) {
+	p.Collection = append(p.Collection, bean)
+}
+
+// Get collection of bean
+func (p *JobBeans) Get() []IPersistent {
+	return p.Collection
+}
@@ -34,6 +34,8 @@ type NodeBean struct {
 	Type string `json:"type"`
 	// Extended internal store
 	Extended map[string]interface{} `json:"extended"`
+	// Version stored with the row
+	Version int64 `json:"-"`
 }
 
 // INodeBean interface
@@ -53,6 +55,16 @@ func (p *NodeBean) New() INodeBean {
 	return &bean
 }
 
+// GetVersion get stored version
+func (p *NodeBean) GetVersion() int64 {
+	return p.Version
+}
+
+// SetVersion set stored version
+func (p *NodeBean) SetVersion(version int64) {
+	p.Version = version
+}
+
 // GetEntityName get set name
 func (p *NodeBean) GetEntityName() string {
 	return "NodeBean"
@@ -120,9 +132,13 @@ func (p *NodeBean) GetTimestamp() JSONTime {
 	return p.Timestamp
 }
 
-// Copy retrieve ID
+// Copy retrieve ID, extended vars are not shared
 func (p *NodeBean) Copy() IPersistent {
 	clone := *p
+	clone.Extended = make(map[string]interface{})
+	for k, v := range p.Extended {
+		clone.Extended[k] = v
+	}
 	return &clone
 }
 
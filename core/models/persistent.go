@@ -66,6 +66,19 @@ type IPersistent interface {
 	GetExtend() map[string]interface{}
 }
 
+// IVersioned persistent bean with a stored version, used for optimistic
+// concurrency; 0 means unknown
+type IVersioned interface {
+	GetVersion() int64
+	SetVersion(int64)
+}
+
+// IValidated persistent bean with its own validation, called after its
+// @validate rules, see engine.Validate
+type IValidated interface {
+	Validate() error
+}
+
 // IPersistents interface
 type IPersistents interface {
 	Add(IPersistent)
@@ -0,0 +1,110 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package models
+
+import (
+	"encoding/json"
+)
+
+// ProjectionBean a partial view of a persistent bean, with only some of
+// its fields
+type ProjectionBean struct {
+	// Entity name
+	entity string
+	// Fields selected
+	Fields map[string]interface{}
+}
+
+// New constructor
+func (p *ProjectionBean) New(entity string, fields map[string]interface{}) IPersistent {
+	bean := ProjectionBean{entity: entity, Fields: fields}
+	if bean.Fields == nil {
+		bean.Fields = make(map[string]interface{})
+	}
+	return &bean
+}
+
+// MarshalJSON only selected fields
+func (p *ProjectionBean) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.Fields)
+}
+
+// GetEntityName get set name
+func (p *ProjectionBean) GetEntityName() string {
+	return p.entity
+}
+
+// GetID retrieve ID
+func (p *ProjectionBean) GetID() string {
+	id, _ := p.Fields["id"].(string)
+	return id
+}
+
+// SetID retrieve ID
+func (p *ProjectionBean) SetID(ID string) {
+	p.Fields["id"] = ID
+}
+
+// GetTimestamp get timestamp, if selected
+func (p *ProjectionBean) GetTimestamp() JSONTime {
+	var stamp JSONTime
+	if value, ok := p.Fields["timestamp"].(string); ok {
+		stamp.UnmarshalJSON([]byte(value))
+	}
+	return stamp
+}
+
+// SetTimestamp set timestamp
+func (p *ProjectionBean) SetTimestamp(stamp JSONTime) {
+	data, _ := stamp.MarshalJSON()
+	var value string
+	json.Unmarshal(data, &value)
+	p.Fields["timestamp"] = value
+}
+
+// Copy this projection
+func (p *ProjectionBean) Copy() IPersistent {
+	fields := make(map[string]interface{})
+	for k, v := range p.Fields {
+		fields[k] = v
+	}
+	return (&ProjectionBean{}).New(p.entity, fields)
+}
+
+// Extend vars
+func (p *ProjectionBean) Extend(e map[string]interface{}) {
+	extended := p.GetExtend()
+	if extended == nil {
+		extended = make(map[string]interface{})
+		p.Fields["extended"] = extended
+	}
+	for k, v := range e {
+		extended[k] = v
+	}
+}
+
+// GetExtend vars, if selected
+func (p *ProjectionBean) GetExtend() map[string]interface{} {
+	extended, _ := p.Fields["extended"].(map[string]interface{})
+	return extended
+}
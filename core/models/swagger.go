@@ -86,11 +86,12 @@ type SwaggerMethodBody struct {
 
 // SwaggerMethodParamBody the parameter block
 type SwaggerMethodParamBody struct {
-	In          string `json:"in"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Required    bool   `json:"required"`
-	Type        string `json:"type"`
+	In          string   `json:"in"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Required    bool     `json:"required"`
+	Type        string   `json:"type"`
+	Enum        []string `json:"enum,omitempty"`
 }
 
 // SwaggerMethodResp the docs block
@@ -116,12 +117,25 @@ type SwaggerSecurityDefinitions struct {
 type SwaggerDefinitions struct {
 	Type       string                   `json:"type"`
 	Properties map[string]SwaggerFormat `json:"properties"`
+	Required   []string                 `json:"required,omitempty"`
 }
 
-// SwaggerFormat the definition block
+// SwaggerFormat the definition block, with its validation rules
 type SwaggerFormat struct {
-	Type   string `json:"type"`
-	Format string `json:"format"`
+	Type          string                   `json:"type,omitempty"`
+	Format        string                   `json:"format,omitempty"`
+	Pattern       string                   `json:"pattern,omitempty"`
+	Enum          []string                 `json:"enum,omitempty"`
+	Minimum       *float64                 `json:"minimum,omitempty"`
+	Maximum       *float64                 `json:"maximum,omitempty"`
+	MinLength     *int                     `json:"minLength,omitempty"`
+	MaxLength     *int                     `json:"maxLength,omitempty"`
+	MinItems      *int                     `json:"minItems,omitempty"`
+	MaxItems      *int                     `json:"maxItems,omitempty"`
+	MinProperties *int                     `json:"minProperties,omitempty"`
+	MaxProperties *int                     `json:"maxProperties,omitempty"`
+	Properties    map[string]SwaggerFormat `json:"properties,omitempty"`
+	Required      []string                 `json:"required,omitempty"`
 }
 
 // SwaggerXML the definition block
@@ -0,0 +1,227 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package winter
+
+import (
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	// proxies by intercepted interface type
+	proxies = make(map[reflect.Type]ProxyFactory)
+)
+
+// ProxyFactory wrap a bean in a proxy routing its methods through aspects
+type ProxyFactory func(name string, bean interface{}, aspects IAspects) interface{}
+
+// RegisterProxy declare the proxy of an interface given as (*IFoo)(nil),
+// autowired fields of this interface type receive the proxy
+func RegisterProxy(iface interface{}, factory ProxyFactory) {
+	proxies[reflect.TypeOf(iface).Elem()] = factory
+}
+
+// Invocation a method call going through interceptors
+type Invocation struct {
+	// Bean name
+	Bean string
+	// Target bean
+	Target interface{}
+	// Method name
+	Method string
+	// Args method arguments, interceptors may change them
+	Args []interface{}
+	// remaining interceptors
+	chain   []IInterceptor
+	proceed func([]interface{}) []interface{}
+}
+
+// Proceed call the next interceptor, then the method with current Args,
+// and return the method results
+func (i *Invocation) Proceed() []interface{} {
+	if len(i.chain) == 0 {
+		return i.proceed(i.Args)
+	}
+	next := *i
+	next.chain = i.chain[1:]
+	return i.chain[0].Intercept(&next)
+}
+
+// IInterceptor wrap a method call, it may inspect arguments, return its
+// own results without calling Proceed or post-process results
+type IInterceptor interface {
+	Intercept(*Invocation) []interface{}
+}
+
+// InterceptorFunc function adapter of IInterceptor
+type InterceptorFunc func(*Invocation) []interface{}
+
+// Intercept call this function
+func (f InterceptorFunc) Intercept(i *Invocation) []interface{} {
+	return f(i)
+}
+
+// Pointcut select intercepted methods, empty criteria match everything
+type Pointcut struct {
+	// Beans bean name pattern (path.Match syntax)
+	Beans string
+	// Interface implemented by bean, given as (*IFoo)(nil)
+	Interface interface{}
+	// Tag listed in an @aspect field tag of bean
+	Tag string
+	// Methods method name pattern (path.Match syntax)
+	Methods string
+}
+
+// interception an interceptor and its pointcut
+type interception struct {
+	pointcut    Pointcut
+	interceptor IInterceptor
+}
+
+// Aspects interceptors registry
+type Aspects struct {
+	*Service
+	// interceptions in registration order
+	interceptions []interception
+	// chains by bean and method
+	chains map[string][]IInterceptor
+	mutex  sync.RWMutex
+}
+
+// IAspects interface
+type IAspects interface {
+	IService
+	// Method
+	Intercept(pointcut Pointcut, interceptor IInterceptor)
+	Invoke(bean string, target interface{}, method string, args []interface{}, proceed func([]interface{}) []interface{}) []interface{}
+	Proxy(name string, typ reflect.Type, bean interface{}) interface{}
+}
+
+// New constructor
+func (a *Aspects) New() IAspects {
+	bean := Aspects{Service: &Service{Bean: &Bean{}}}
+	bean.interceptions = make([]interception, 0)
+	bean.chains = make(map[string][]IInterceptor)
+	return &bean
+}
+
+// Init this bean
+func (a *Aspects) Init() error {
+	return nil
+}
+
+// Intercept register an interceptor, interceptors are called in
+// registration order
+func (a *Aspects) Intercept(pointcut Pointcut, interceptor IInterceptor) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.interceptions = append(a.interceptions, interception{pointcut: pointcut, interceptor: interceptor})
+	a.chains = make(map[string][]IInterceptor)
+}
+
+// Invoke call proceed through all interceptors of this bean method
+func (a *Aspects) Invoke(bean string, target interface{}, method string, args []interface{}, proceed func([]interface{}) []interface{}) []interface{} {
+	invocation := &Invocation{Bean: bean, Target: target, Method: method, Args: args, chain: a.chain(bean, target, method), proceed: proceed}
+	return invocation.Proceed()
+}
+
+// Proxy wrap this bean if a proxy is declared for this type
+func (a *Aspects) Proxy(name string, typ reflect.Type, bean interface{}) interface{} {
+	if factory, ok := proxies[typ]; ok && bean != nil {
+		return factory(name, bean, a)
+	}
+	return bean
+}
+
+// chain all interceptors matching this bean method
+func (a *Aspects) chain(bean string, target interface{}, method string) []IInterceptor {
+	var key = bean + "." + method
+	a.mutex.RLock()
+	chain, ok := a.chains[key]
+	a.mutex.RUnlock()
+	if ok {
+		return chain
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	chain = make([]IInterceptor, 0)
+	for _, candidate := range a.interceptions {
+		if a.match(candidate.pointcut, bean, target, method) {
+			chain = append(chain, candidate.interceptor)
+		}
+	}
+	a.chains[key] = chain
+	return chain
+}
+
+// match check all criteria of this pointcut
+func (a *Aspects) match(pointcut Pointcut, bean string, target interface{}, method string) bool {
+	if len(pointcut.Beans) > 0 {
+		if ok, _ := path.Match(pointcut.Beans, bean); !ok {
+			return false
+		}
+	}
+	if len(pointcut.Methods) > 0 {
+		if ok, _ := path.Match(pointcut.Methods, method); !ok {
+			return false
+		}
+	}
+	if pointcut.Interface != nil {
+		if target == nil || !reflect.TypeOf(target).Implements(reflect.TypeOf(pointcut.Interface).Elem()) {
+			return false
+		}
+	}
+	if len(pointcut.Tag) > 0 {
+		return a.tagged(reflect.ValueOf(target), pointcut.Tag)
+	}
+	return true
+}
+
+// tagged check if a field of this bean, or of its embedded structs,
+// lists this tag in @aspect
+func (a *Aspects) tagged(val reflect.Value, tag string) bool {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return false
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Type().Field(i)
+		for _, name := range strings.Split(field.Tag.Get("@aspect"), ",") {
+			if strings.TrimSpace(name) == tag {
+				return true
+			}
+		}
+		if field.Anonymous && a.tagged(val.Field(i), tag) {
+			return true
+		}
+	}
+	return false
+}
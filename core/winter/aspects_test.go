@@ -0,0 +1,157 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package winter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// IShouter an intercepted bean type
+type IShouter interface {
+	IBean
+	Shout(text string) string
+}
+
+// shouter upper case its text
+type shouter struct {
+	*Service
+	Audited interface{} `@aspect:"audit, timing"`
+}
+
+// Shout upper case text
+func (p *shouter) Shout(text string) string {
+	return strings.ToUpper(text)
+}
+
+// shouterProxy route Shout through aspects
+type shouterProxy struct {
+	IShouter
+	name    string
+	aspects IAspects
+}
+
+// Shout through interceptors
+func (p *shouterProxy) Shout(text string) string {
+	results := p.aspects.Invoke(p.name, p.IShouter, "Shout", []interface{}{text}, func(args []interface{}) []interface{} {
+		return []interface{}{p.IShouter.Shout(args[0].(string))}
+	})
+	return results[0].(string)
+}
+
+// listening a bean autowired with a shouter
+type listening struct {
+	*Service
+	Shouter IShouter `@autowired:"shouter"`
+}
+
+func init() {
+	RegisterProxy((*IShouter)(nil), func(name string, bean interface{}, aspects IAspects) interface{} {
+		return &shouterProxy{IShouter: bean.(IShouter), name: name, aspects: aspects}
+	})
+}
+
+// tracing an interceptor recording its name, then proceeding
+func tracing(name string, calls *[]string) IInterceptor {
+	return InterceptorFunc(func(i *Invocation) []interface{} {
+		*calls = append(*calls, name)
+		return i.Proceed()
+	})
+}
+
+func TestInterceptors(t *testing.T) {
+	target := &shouter{Service: &Service{&Bean{}}}
+	tests := []struct {
+		name     string
+		pointcut Pointcut
+		// interceptor of this pointcut
+		interceptor func(calls *[]string) IInterceptor
+		result      string
+		calls       []string
+	}{
+		{"proceed", Pointcut{}, func(calls *[]string) IInterceptor { return tracing("first", calls) }, "HELLO", []string{"first", "second", "method"}},
+		{"short-circuit", Pointcut{Methods: "Shout"}, func(calls *[]string) IInterceptor {
+			return InterceptorFunc(func(i *Invocation) []interface{} {
+				*calls = append(*calls, "veto")
+				return []interface{}{"vetoed"}
+			})
+		}, "vetoed", []string{"veto"}},
+		{"arguments", Pointcut{Beans: "shout*"}, func(calls *[]string) IInterceptor {
+			return InterceptorFunc(func(i *Invocation) []interface{} {
+				i.Args[0] = "changed"
+				return i.Proceed()
+			})
+		}, "CHANGED", []string{"second", "method"}},
+		{"results", Pointcut{Interface: (*IShouter)(nil)}, func(calls *[]string) IInterceptor {
+			return InterceptorFunc(func(i *Invocation) []interface{} {
+				results := i.Proceed()
+				return []interface{}{results[0].(string) + "!"}
+			})
+		}, "HELLO!", []string{"second", "method"}},
+		{"tag", Pointcut{Tag: "timing"}, func(calls *[]string) IInterceptor { return tracing("tagged", calls) }, "HELLO", []string{"tagged", "second", "method"}},
+		{"bean mismatch", Pointcut{Beans: "other"}, func(calls *[]string) IInterceptor { return tracing("first", calls) }, "HELLO", []string{"second", "method"}},
+		{"method mismatch", Pointcut{Methods: "Whisper"}, func(calls *[]string) IInterceptor { return tracing("first", calls) }, "HELLO", []string{"second", "method"}},
+		{"tag mismatch", Pointcut{Tag: "security"}, func(calls *[]string) IInterceptor { return tracing("first", calls) }, "HELLO", []string{"second", "method"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := make([]string, 0)
+			aspects := (&Aspects{}).New()
+			aspects.Intercept(test.pointcut, test.interceptor(&calls))
+			aspects.Intercept(Pointcut{}, tracing("second", &calls))
+			results := aspects.Invoke("shouter", target, "Shout", []interface{}{"hello"}, func(args []interface{}) []interface{} {
+				calls = append(calls, "method")
+				return []interface{}{target.Shout(args[0].(string))}
+			})
+			if results[0] != test.result || !reflect.DeepEqual(calls, test.calls) {
+				t.Errorf("got %v %v", results, calls)
+			}
+		})
+	}
+}
+
+func TestProxy(t *testing.T) {
+	manager, err := (&Builder{}).New("test").
+		Register("shouter", func() IBean { return &shouter{Service: &Service{&Bean{}}} }).
+		Register("listening", func() IBean { return &listening{Service: &Service{&Bean{}}} }).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.GetAspects().Intercept(Pointcut{Beans: "shouter", Methods: "Shout"}, InterceptorFunc(func(i *Invocation) []interface{} {
+		if i.Args[0] == "secret" {
+			return []interface{}{"denied"}
+		}
+		return i.Proceed()
+	}))
+	if err := manager.Start(nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Shutdown()
+	// autowired fields receive the proxy
+	bean := manager.GetBean("listening").(*listening)
+	if got := []string{bean.Shouter.Shout("hello"), bean.Shouter.Shout("secret")}; !reflect.DeepEqual(got, []string{"HELLO", "denied"}) {
+		t.Errorf("got %v", got)
+	}
+}
@@ -0,0 +1,122 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package winter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// welcome another IGreeter
+type welcome struct {
+	*Service
+}
+
+// Greet say welcome
+func (p *welcome) Greet() string {
+	return "welcome"
+}
+
+// greeted a bean autowired with greeters in every way
+type greeted struct {
+	*Service
+	ByType      IGreeter   `@autowired:"" @optional:"true"`
+	ByQualifier IGreeter   `@autowired:"" @qualifier:"welcome" @optional:"true"`
+	All         []IGreeter `@autowired:""`
+}
+
+// strict a bean needing a single greeter
+type strict struct {
+	*Service
+	Greeter IGreeter `@autowired:""`
+}
+
+// greetings of these greeters
+func greetings(greeters ...IGreeter) []string {
+	result := make([]string, 0)
+	for _, greeter := range greeters {
+		if greeter == nil {
+			result = append(result, "")
+			continue
+		}
+		result = append(result, greeter.Greet())
+	}
+	return result
+}
+
+func TestAutowireByType(t *testing.T) {
+	tests := []struct {
+		name string
+		// registered greeters
+		greeters []string
+		// greetings of ByType, ByQualifier then All
+		greetings []string
+	}{
+		{"no greeter", nil, []string{"", ""}},
+		{"single greeter", []string{"hello"}, []string{"hello", "", "hello"}},
+		{"two greeters", []string{"hello", "welcome"}, []string{"", "welcome", "hello", "welcome"}},
+	}
+	factories := map[string]func() IBean{
+		"hello":   newGreeter,
+		"welcome": func() IBean { return &welcome{&Service{&Bean{}}} },
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builder := (&Builder{}).New("test")
+			for _, name := range test.greeters {
+				builder.Register(name, factories[name])
+			}
+			builder.Register("greeted", func() IBean { return &greeted{Service: &Service{&Bean{}}} })
+			manager, err := builder.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := manager.Start(nil, ""); err != nil {
+				t.Fatal(err)
+			}
+			defer manager.Shutdown()
+			bean := manager.GetBean("greeted").(*greeted)
+			got := append(greetings(bean.ByType, bean.ByQualifier), greetings(bean.All...)...)
+			if !reflect.DeepEqual(got, test.greetings) {
+				t.Errorf("got %q", got)
+			}
+		})
+	}
+}
+
+func TestAutowireAmbiguous(t *testing.T) {
+	manager, err := (&Builder{}).New("test").
+		Register("hello", newGreeter).
+		Register("welcome", func() IBean { return &welcome{&Service{&Bean{}}} }).
+		Register("strict", func() IBean { return &strict{Service: &Service{&Bean{}}} }).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = manager.Start(nil, "")
+	errs, ok := err.(BootErrors)
+	if !ok || len(errs) != 1 || errs[0].Phase != "Inject" || errs[0].Dependency != "winter.IGreeter" || !strings.Contains(errs[0].Error(), "use @qualifier") {
+		t.Errorf("got %v", err)
+	}
+}
@@ -34,6 +34,7 @@ type IBean interface {
 	SetName(name string)
 	PostConstruct(string) error
 	Validate(string) error
+	PreDestroy(string) error
 }
 
 // Inject Init this bean
@@ -51,6 +52,11 @@ func (bean *Bean) Validate(string) error {
 	return nil
 }
 
+// PreDestroy release this bean
+func (bean *Bean) PreDestroy(string) error {
+	return nil
+}
+
 // SetName fix the bean name
 func (bean *Bean) SetName(name string) {
 	bean.Name = name
@@ -0,0 +1,163 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package winter
+
+import (
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// definitions declared by packages, in declaration order
+	definitions = make([]Definition, 0)
+)
+
+// Definition a named bean factory, see Manager.RegisterScoped
+type Definition struct {
+	Name    string
+	Scope   Scope
+	Factory interface{}
+	// Conditions checked at boot, see Manager.RegisterConditional
+	Conditions []Condition
+}
+
+// Define declare a default singleton bean, an instance is also registered
+// on Helper
+func Define(name string, factory func() IBean) {
+	DefineScoped(name, Singleton, factory)
+}
+
+// DefineScoped declare a default bean with this scope, it is also
+// registered on Helper
+func DefineScoped(name string, scope Scope, factory interface{}) {
+	DefineConditional(name, scope, factory)
+}
+
+// DefineConditional declare a default bean registered only when all
+// conditions match at boot, it is also registered on Helper
+func DefineConditional(name string, scope Scope, factory interface{}, conditions ...Condition) {
+	definitions = append(definitions, Definition{Name: name, Scope: scope, Factory: factory, Conditions: conditions})
+	if err := Helper.RegisterConditional(name, scope, factory, conditions...); err != nil {
+		log.WithFields(log.Fields{
+			"name":  name,
+			"error": err,
+		}).Error("Define")
+	}
+}
+
+// Definitions all default bean definitions
+func Definitions() []Definition {
+	return append([]Definition{}, definitions...)
+}
+
+// Builder build an isolated manager, its beans are not shared with Helper
+// nor with any other manager
+type Builder struct {
+	name        string
+	definitions []Definition
+	overrides   map[string]IBean
+	// override names in call order
+	names      []string
+	properties map[string]string
+}
+
+// New constructor
+func (b *Builder) New(name string) *Builder {
+	builder := Builder{name: name}
+	builder.definitions = make([]Definition, 0)
+	builder.overrides = make(map[string]IBean)
+	builder.properties = make(map[string]string)
+	return &builder
+}
+
+// WithDefaults add all default bean definitions
+func (b *Builder) WithDefaults() *Builder {
+	b.definitions = append(b.definitions, Definitions()...)
+	return b
+}
+
+// Register add a singleton bean definition
+func (b *Builder) Register(name string, factory func() IBean) *Builder {
+	return b.RegisterScoped(name, Singleton, factory)
+}
+
+// RegisterScoped add a bean definition with this scope
+func (b *Builder) RegisterScoped(name string, scope Scope, factory interface{}) *Builder {
+	return b.RegisterConditional(name, scope, factory)
+}
+
+// RegisterConditional add a bean definition registered only when all
+// conditions match at boot
+func (b *Builder) RegisterConditional(name string, scope Scope, factory interface{}, conditions ...Condition) *Builder {
+	b.definitions = append(b.definitions, Definition{Name: name, Scope: scope, Factory: factory, Conditions: conditions})
+	return b
+}
+
+// Override replace the bean built for this name by a singleton, whatever
+// its conditions; a bean without definition is simply added
+func (b *Builder) Override(name string, bean IBean) *Builder {
+	if _, ok := b.overrides[name]; !ok {
+		b.names = append(b.names, name)
+	}
+	b.overrides[name] = bean
+	return b
+}
+
+// Property set a configuration property
+func (b *Builder) Property(key string, value string) *Builder {
+	b.properties[key] = value
+	return b
+}
+
+// Build create the manager, load its configuration and register all
+// beans, it is not booted
+func (b *Builder) Build() (IManager, error) {
+	manager := (&Manager{}).New(b.name)
+	for key, value := range b.properties {
+		manager.GetConfig().Set(key, value)
+	}
+	if err := manager.GetConfig().Load(); err != nil {
+		return nil, err
+	}
+	registered := make(map[string]bool)
+	for _, definition := range b.definitions {
+		if registered[definition.Name] {
+			return nil, errors.New("Bean '" + definition.Name + "' is defined twice")
+		}
+		registered[definition.Name] = true
+		if bean, ok := b.overrides[definition.Name]; ok {
+			manager.Register(definition.Name, bean)
+			continue
+		}
+		if err := manager.RegisterConditional(definition.Name, definition.Scope, definition.Factory, definition.Conditions...); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range b.names {
+		if !registered[name] {
+			manager.Register(name, b.overrides[name])
+		}
+	}
+	return manager, nil
+}
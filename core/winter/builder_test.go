@@ -0,0 +1,94 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package winter
+
+import (
+	"testing"
+)
+
+// fakeGreeter an IGreeter replacing the default one
+type fakeGreeter struct {
+	*Service
+}
+
+// Greet say fake
+func (p *fakeGreeter) Greet() string {
+	return "fake"
+}
+
+func TestBuilderIsolation(t *testing.T) {
+	builder := (&Builder{}).New("test").Register("greeter", newGreeter)
+	first, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, manager := range []IManager{first, second} {
+		if err := manager.Start(nil, ""); err != nil {
+			t.Fatal(err)
+		}
+		defer manager.Shutdown()
+	}
+	if first.GetBean("greeter") == second.GetBean("greeter") || first.GetEventBus() == second.GetEventBus() {
+		t.Error("managers share beans")
+	}
+	if Helper.Contains("greeter") {
+		t.Error("Helper knows a built bean")
+	}
+}
+
+func TestBuilderOverride(t *testing.T) {
+	manager, err := (&Builder{}).New("test").
+		RegisterConditional("greeter", Singleton, newGreeter, OnProperty("greeter", "on", false)).
+		Override("greeter", &fakeGreeter{&Service{&Bean{}}}).
+		Override("extra", &greeter{&Service{&Bean{}}}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Start(nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Shutdown()
+	// overrides win whatever their conditions, beans without definition
+	// are added
+	if greeter, ok := manager.GetBean("greeter").(IGreeter); !ok || greeter.Greet() != "fake" {
+		t.Errorf("got %v", manager.GetBean("greeter"))
+	}
+	if !manager.Contains("extra") {
+		t.Error("override without definition is missing")
+	}
+}
+
+func TestBuilderDuplicate(t *testing.T) {
+	_, err := (&Builder{}).New("test").
+		Register("greeter", newGreeter).
+		Register("greeter", newGreeter).
+		Build()
+	if err == nil || err.Error() != "Bean 'greeter' is defined twice" {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package winter
+
+import (
+	"reflect"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Condition decide if a conditional bean is registered, it is evaluated
+// by Start once configuration is loaded
+type Condition struct {
+	// Description for logs
+	Description string
+	// Matches this manager
+	Matches func(IManager) bool
+	// fallback conditions are evaluated once all other beans are registered
+	fallback bool
+}
+
+// conditional a bean definition waiting for its conditions
+type conditional struct {
+	definition Definition
+	conditions []Condition
+}
+
+// OnProperty match when this property has this value, an empty value
+// matches any non empty property; matchIfMissing match unset properties
+func OnProperty(key string, value string, matchIfMissing bool) Condition {
+	return Condition{
+		Description: "property " + key + "=" + value,
+		Matches: func(m IManager) bool {
+			current, ok := m.GetConfig().Get(key)
+			if !ok {
+				return matchIfMissing
+			}
+			if len(value) == 0 {
+				return len(current) > 0
+			}
+			return strings.EqualFold(current, value)
+		},
+	}
+}
+
+// OnProfile match when one of these profiles is active, a profile
+// prefixed by ! match when it is not active
+func OnProfile(profiles ...string) Condition {
+	return Condition{
+		Description: "profile " + strings.Join(profiles, ","),
+		Matches: func(m IManager) bool {
+			for _, profile := range profiles {
+				if strings.HasPrefix(profile, "!") {
+					if !m.GetConfig().IsActive(profile[1:]) {
+						return true
+					}
+				} else if m.GetConfig().IsActive(profile) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// OnBean match when this bean is registered
+func OnBean(name string) Condition {
+	return Condition{
+		Description: "bean " + name,
+		Matches: func(m IManager) bool {
+			return m.Contains(name)
+		},
+		fallback: true,
+	}
+}
+
+// OnMissingBean match when this bean is not registered, it declares a
+// default bean that any other registration overrides
+func OnMissingBean(name string) Condition {
+	return Condition{
+		Description: "missing bean " + name,
+		Matches: func(m IManager) bool {
+			return !m.Contains(name)
+		},
+		fallback: true,
+	}
+}
+
+// OnMissingType match when no bean implements this interface, given as
+// (*IFoo)(nil)
+func OnMissingType(iface interface{}) Condition {
+	return Condition{
+		Description: "missing type " + reflect.TypeOf(iface).Elem().String(),
+		Matches: func(m IManager) bool {
+			return len(m.Implementations(iface)) == 0
+		},
+		fallback: true,
+	}
+}
+
+// RegisterConditional register a bean factory (see RegisterScoped) only
+// when all conditions match
+func (m *Manager) RegisterConditional(name string, scope Scope, factory interface{}, conditions ...Condition) error {
+	if len(conditions) == 0 {
+		return m.RegisterScoped(name, scope, factory)
+	}
+	m.conditionals = append(m.conditionals, &conditional{
+		definition: Definition{Name: name, Scope: scope, Factory: factory},
+		conditions: conditions,
+	})
+	return nil
+}
+
+// Contains check if this bean is registered, scoped beans are not created
+func (m *Manager) Contains(name string) bool {
+	if _, ok := m.scopes[name]; ok {
+		return true
+	}
+	_, ok := m.MapOfBeans[name]
+	return ok
+}
+
+// Implementations names of all beans implementing this interface, given
+// as (*IFoo)(nil), scoped beans are not created
+func (m *Manager) Implementations(iface interface{}) []string {
+	typ := reflect.TypeOf(iface).Elem()
+	names := make([]string, 0)
+	for index, bean := range m.ArrayOfBeans {
+		if bean != nil && reflect.TypeOf(bean).Implements(typ) {
+			names = append(names, m.ArrayOfBeanNames[index])
+		}
+	}
+	for _, name := range m.scopedNames {
+		if m.scopes[name].typ.Implements(typ) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// evaluate all conditional beans, fallback conditions last
+func (m *Manager) evaluate() BootErrors {
+	errs := make(BootErrors, 0)
+	for _, fallback := range []bool{false, true} {
+		for _, candidate := range m.conditionals {
+			if candidate.fallback() != fallback {
+				continue
+			}
+			if !candidate.matches(m) {
+				continue
+			}
+			err := m.RegisterScoped(candidate.definition.Name, candidate.definition.Scope, candidate.definition.Factory)
+			if err != nil {
+				errs = append(errs, &BootError{Bean: candidate.definition.Name, Phase: "Condition", Err: err})
+			}
+		}
+	}
+	m.conditionals = nil
+	return errs
+}
+
+// fallback check if any condition is a fallback one
+func (c *conditional) fallback() bool {
+	for _, condition := range c.conditions {
+		if condition.fallback {
+			return true
+		}
+	}
+	return false
+}
+
+// matches check all conditions
+func (c *conditional) matches(m IManager) bool {
+	for _, condition := range c.conditions {
+		if !condition.Matches(m) {
+			log.WithFields(log.Fields{
+				"name":      c.definition.Name,
+				"condition": condition.Description,
+			}).Info("Bean skipped")
+			return false
+		}
+	}
+	return true
+}
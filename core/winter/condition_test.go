@@ -0,0 +1,98 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package winter
+
+import (
+	"testing"
+)
+
+// IGreeter a bean type for OnMissingType
+type IGreeter interface {
+	IBean
+	Greet() string
+}
+
+type greeter struct {
+	*Service
+}
+
+// Greet say hello
+func (p *greeter) Greet() string {
+	return "hello"
+}
+
+func newGreeter() IBean {
+	return &greeter{Service: &Service{&Bean{}}}
+}
+
+func TestConditions(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties map[string]string
+		// other registers a singleton "other" greeter
+		other      bool
+		conditions []Condition
+		registered bool
+	}{
+		{"property value", map[string]string{"feature": "on"}, false, []Condition{OnProperty("feature", "ON", false)}, true},
+		{"property other value", map[string]string{"feature": "off"}, false, []Condition{OnProperty("feature", "on", false)}, false},
+		{"property any value", map[string]string{"feature": "x"}, false, []Condition{OnProperty("feature", "", false)}, true},
+		{"property missing", nil, false, []Condition{OnProperty("feature", "on", false)}, false},
+		{"property match if missing", nil, false, []Condition{OnProperty("feature", "on", true)}, true},
+		{"profile active", map[string]string{ProfilesKey: "dev, test"}, false, []Condition{OnProfile("test")}, true},
+		{"profile inactive", map[string]string{ProfilesKey: "dev"}, false, []Condition{OnProfile("prod")}, false},
+		{"negated profile", map[string]string{ProfilesKey: "dev"}, false, []Condition{OnProfile("!prod")}, true},
+		{"negated active profile", map[string]string{ProfilesKey: "prod"}, false, []Condition{OnProfile("!prod")}, false},
+		{"bean", nil, true, []Condition{OnBean("other")}, true},
+		{"missing bean", nil, false, []Condition{OnBean("other")}, false},
+		{"on missing bean", nil, false, []Condition{OnMissingBean("other")}, true},
+		{"on missing bean present", nil, true, []Condition{OnMissingBean("other")}, false},
+		{"on missing type", nil, false, []Condition{OnMissingType((*IGreeter)(nil))}, true},
+		{"on missing type present", nil, true, []Condition{OnMissingType((*IGreeter)(nil))}, false},
+		{"all conditions", map[string]string{"feature": "on"}, false, []Condition{OnProperty("feature", "on", false), OnProfile("prod")}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builder := (&Builder{}).New("test")
+			for key, value := range test.properties {
+				builder.Property(key, value)
+			}
+			// conditional first, fallback conditions still see other
+			builder.RegisterConditional("conditional", Singleton, func() IBean { return &Service{&Bean{}} }, test.conditions...)
+			if test.other {
+				builder.Register("other", newGreeter)
+			}
+			manager, err := builder.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := manager.Start(nil, ""); err != nil {
+				t.Fatal(err)
+			}
+			defer manager.Shutdown()
+			if manager.Contains("conditional") != test.registered {
+				t.Errorf("registered is %v, expected %v", manager.Contains("conditional"), test.registered)
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package winter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	log "github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// ProfilesKey property holding active profiles (comma separated)
+	ProfilesKey = "winter.profiles"
+	// ConfigPathKey property holding the configuration directory
+	ConfigPathKey = "winter.config"
+)
+
+func init() {
+	flag.String(ProfilesKey, "", "active profiles (comma separated)")
+	flag.String(ConfigPathKey, ".", "configuration directory")
+}
+
+// Config externalised configuration, a property is resolved from values
+// Set by code, then command line flags, then environment (store.path is read from STORE_PATH), then
+// profile files application-<profile>.(yml|yaml|toml|json), then
+// application.(yml|yaml|toml|json)
+type Config struct {
+	*Service
+	// Path search path for configuration files
+	Path string
+	// Profiles active profiles
+	Profiles []string
+	// flattened file properties
+	properties map[string]string
+	// flags set on the command line
+	flags map[string]string
+	// properties set by code
+	overrides map[string]string
+}
+
+// IConfig interface
+type IConfig interface {
+	IService
+	// Method
+	Load() error
+	Get(key string) (string, bool)
+	GetString(key string, defaultValue string) string
+	Set(key string, value string)
+	IsActive(profile string) bool
+	ActiveProfiles() []string
+	Bind(name string, intf interface{}) error
+}
+
+// New constructor
+func (c *Config) New() IConfig {
+	bean := Config{Service: &Service{Bean: &Bean{}}, Path: "."}
+	bean.properties = make(map[string]string)
+	bean.flags = make(map[string]string)
+	bean.overrides = make(map[string]string)
+	return &bean
+}
+
+// Init this bean
+func (c *Config) Init() error {
+	return nil
+}
+
+// Load read flags, then base and profile files
+func (c *Config) Load() error {
+	flag.Visit(func(f *flag.Flag) {
+		c.flags[f.Name] = f.Value.String()
+	})
+	c.Path = c.GetString(ConfigPathKey, c.Path)
+	if err := c.read("application"); err != nil {
+		return err
+	}
+	c.Profiles = make([]string, 0)
+	for _, profile := range strings.Split(c.GetString(ProfilesKey, ""), ",") {
+		if profile = strings.TrimSpace(profile); len(profile) > 0 {
+			c.Profiles = append(c.Profiles, profile)
+		}
+	}
+	for _, profile := range c.Profiles {
+		if err := c.read("application-" + profile); err != nil {
+			return err
+		}
+	}
+	log.WithFields(log.Fields{
+		"path":     c.Path,
+		"profiles": c.Profiles,
+	}).Info("Config loaded")
+	return nil
+}
+
+// read all files with this base name, missing files are ignored
+func (c *Config) read(base string) error {
+	for _, ext := range []string{".yml", ".yaml", ".toml", ".json"} {
+		var path = filepath.Join(c.Path, base+ext)
+		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		values := make(map[string]interface{})
+		switch ext {
+		case ".toml":
+			err = toml.Unmarshal(data, &values)
+		case ".json":
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			decoder.UseNumber()
+			err = decoder.Decode(&values)
+		default:
+			err = yaml.Unmarshal(data, &values)
+		}
+		if err != nil {
+			return errors.New("Unable to parse " + path + ": " + err.Error())
+		}
+		c.flatten("", values)
+		log.WithFields(log.Fields{
+			"path": path,
+		}).Info("Config file")
+	}
+	return nil
+}
+
+// flatten store nested values with dotted keys, lists are comma separated
+func (c *Config) flatten(prefix string, value interface{}) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for k, v := range typed {
+			c.flatten(c.key(prefix, k), v)
+		}
+	case map[interface{}]interface{}:
+		for k, v := range typed {
+			c.flatten(c.key(prefix, fmt.Sprintf("%v", k)), v)
+		}
+	case []interface{}:
+		items := make([]string, 0, len(typed))
+		for _, item := range typed {
+			items = append(items, fmt.Sprintf("%v", item))
+		}
+		c.properties[prefix] = strings.Join(items, ",")
+	case nil:
+		c.properties[prefix] = ""
+	default:
+		c.properties[prefix] = fmt.Sprintf("%v", typed)
+	}
+}
+
+// key join a prefix and a key
+func (c *Config) key(prefix string, key string) string {
+	if len(prefix) == 0 {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// env name of a property, store.path is STORE_PATH
+func (c *Config) env(key string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+// Get resolve a property
+func (c *Config) Get(key string) (string, bool) {
+	if value, ok := c.overrides[key]; ok {
+		return value, true
+	}
+	if value, ok := c.flags[key]; ok {
+		return value, true
+	}
+	if value, ok := os.LookupEnv(c.env(key)); ok {
+		return value, true
+	}
+	value, ok := c.properties[key]
+	return value, ok
+}
+
+// GetString resolve a property or return its default value
+func (c *Config) GetString(key string, defaultValue string) string {
+	if value, ok := c.Get(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// Set a property, it takes precedence over any other source
+func (c *Config) Set(key string, value string) {
+	c.overrides[key] = value
+}
+
+// IsActive check if this profile is active
+func (c *Config) IsActive(profile string) bool {
+	for _, active := range c.Profiles {
+		if active == profile {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveProfiles all active profiles
+func (c *Config) ActiveProfiles() []string {
+	return c.Profiles
+}
+
+// Bind inject properties into the @value fields of this bean, return
+// BootErrors for each unconvertible property
+func (c *Config) Bind(name string, intf interface{}) error {
+	return c.bind(name, reflect.ValueOf(intf), nil).orNil()
+}
+
+// bind fields tagged with @value:"key", @default:"value" is used when
+// the key is not resolved; untagged exported fields are scanned recursively
+func (c *Config) bind(name string, val reflect.Value, errs BootErrors) BootErrors {
+	var kind = val.Type().Kind()
+	if kind == reflect.Interface || kind == reflect.Ptr {
+		if !val.IsNil() {
+			return c.bind(name, val.Elem(), errs)
+		}
+		return errs
+	}
+	if kind != reflect.Struct {
+		return errs
+	}
+	for i := 0; i < val.NumField(); i++ {
+		valueField := val.Field(i)
+		typeField := val.Type().Field(i)
+		if typeField.PkgPath != "" {
+			continue
+		}
+		key, ok := typeField.Tag.Lookup("@value")
+		if !ok {
+			// autowired fields are other beans, they bind themselves
+			if _, ok := typeField.Tag.Lookup("@autowired"); !ok {
+				errs = c.bind(name, valueField, errs)
+			}
+			continue
+		}
+		value, ok := c.Get(key)
+		if !ok {
+			value, ok = typeField.Tag.Lookup("@default")
+		}
+		if !ok {
+			continue
+		}
+		if err := c.convert(value, valueField); err != nil {
+			errs = append(errs, &BootError{Bean: name, Phase: "Inject", Dependency: key, Err: err})
+		}
+	}
+	return errs
+}
+
+// convert a string property to the field type
+func (c *Config) convert(value string, field reflect.Value) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		typed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(typed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		typed, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(typed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		typed, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(typed)
+	case reflect.Float32, reflect.Float64:
+		typed, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(typed)
+	case reflect.Slice:
+		items := make([]string, 0)
+		if len(value) > 0 {
+			items = strings.Split(value, ",")
+		}
+		slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+		for index, item := range items {
+			if err := c.convert(strings.TrimSpace(item), slice.Index(index)); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+	default:
+		return errors.New("unsupported type " + field.Type().String())
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package winter
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// settings a bean with @value fields
+type settings struct {
+	*Service
+	Path     string        `@value:"store.path" @default:"default.db"`
+	Port     int           `@value:"app.port" @default:"-1"`
+	Timeout  time.Duration `@value:"app.timeout" @default:"5s"`
+	Enabled  bool          `@value:"app.enabled"`
+	Tags     []string      `@value:"app.tags"`
+	Missing  string        `@value:"app.missing" @default:"fallback"`
+	Embedded struct {
+		Level string `@value:"logging.level" @default:"debug"`
+	}
+}
+
+// configDir a directory holding these configuration files
+func configDir(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestConfigProfiles(t *testing.T) {
+	dir := configDir(t, map[string]string{
+		"application.yml":       "store:\n  path: base.db\napp:\n  port: 8080\n  tags: [a, b]\n",
+		"application-dev.toml":  "[store]\npath = \"dev.db\"\n",
+		"application-prod.json": `{"store":{"path":"prod.db"},"app":{"port":443}}`,
+	})
+	tests := []struct {
+		name     string
+		profiles string
+		path     string
+		port     string
+	}{
+		{"no profile", "", "base.db", "8080"},
+		{"dev", "dev", "dev.db", "8080"},
+		{"prod", "prod", "prod.db", "443"},
+		{"last profile wins", "prod, dev", "dev.db", "443"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := (&Config{}).New()
+			config.Set(ConfigPathKey, dir)
+			config.Set(ProfilesKey, test.profiles)
+			if err := config.Load(); err != nil {
+				t.Fatal(err)
+			}
+			if path := config.GetString("store.path", ""); path != test.path {
+				t.Errorf("store.path is %s", path)
+			}
+			if port := config.GetString("app.port", ""); port != test.port {
+				t.Errorf("app.port is %s", port)
+			}
+			if tags := config.GetString("app.tags", ""); tags != "a,b" {
+				t.Errorf("app.tags is %s", tags)
+			}
+		})
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	dir := configDir(t, map[string]string{"application.yml": "app:\n  port: 8080\n  timeout: 1s\n"})
+	t.Setenv("APP_PORT", "9090")
+	t.Setenv("APP_TIMEOUT", "2s")
+	config := (&Config{}).New()
+	config.Set(ConfigPathKey, dir)
+	if err := config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	// environment over files, code over environment
+	config.Set("app.timeout", "3s")
+	if port, timeout := config.GetString("app.port", ""), config.GetString("app.timeout", ""); port != "9090" || timeout != "3s" {
+		t.Errorf("got %s %s", port, timeout)
+	}
+}
+
+func TestBind(t *testing.T) {
+	dir := configDir(t, map[string]string{"application.yml": "store:\n  path: base.db\napp:\n  port: 8080\n  timeout: 1m\n  enabled: true\n  tags: [a, b]\nlogging:\n  level: info\n"})
+	manager, err := (&Builder{}).New("test").
+		Property(ConfigPathKey, dir).
+		Register("settings", func() IBean { return &settings{Service: &Service{&Bean{}}} }).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// loaded by Build
+	if err := manager.Start(nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Shutdown()
+	bean := manager.GetBean("settings").(*settings)
+	got := []interface{}{bean.Path, bean.Port, bean.Timeout, bean.Enabled, bean.Tags, bean.Missing, bean.Embedded.Level}
+	expected := []interface{}{"base.db", 8080, time.Minute, true, []string{"a", "b"}, "fallback", "info"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestBindFailure(t *testing.T) {
+	config := (&Config{}).New()
+	config.Set("app.port", "http")
+	err := config.Bind("settings", &settings{Service: &Service{&Bean{}}})
+	errs, ok := err.(BootErrors)
+	if !ok || len(errs) != 1 || errs[0].Bean != "settings" || errs[0].Phase != "Inject" || errs[0].Dependency != "app.port" {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package winter
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// dependencies collect all bean names this value is autowired with
+func (m *Manager) dependencies(val reflect.Value, deps []string) []string {
+	var kind = val.Type().Kind()
+
+	// Interface case and Pointer case
+	if kind == reflect.Interface || kind == reflect.Ptr {
+		if !val.IsNil() {
+			return m.dependencies(val.Elem(), deps)
+		}
+		return deps
+	}
+
+	// Only structs can declare autowired fields
+	if kind != reflect.Struct {
+		return deps
+	}
+
+	for i := 0; i < val.NumField(); i++ {
+		typeField := val.Type().Field(i)
+		if m.isPrivate(typeField) {
+			continue
+		}
+		if beanName, ok := m.autowired(typeField); ok {
+			// collections and providers are injected but do not constrain order
+			if typeField.Type.Kind() == reflect.Slice || typeField.Type.Kind() == reflect.Func {
+				continue
+			}
+			candidates, _ := m.candidates(beanName, typeField)
+			deps = append(deps, candidates...)
+		} else {
+			// autowired fields are excluded for recursive scan
+			deps = m.dependencies(val.Field(i), deps)
+		}
+	}
+	return deps
+}
+
+// order compute bean indexes in dependency order, a bean always comes after
+// the beans it is autowired with; self references, slice and provider fields
+// are ignored, cycles of scoped beans are rejected as well
+func (m *Manager) order() ([]int, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	index := make(map[string]int)
+	for i, name := range m.ArrayOfBeanNames {
+		index[name] = i
+	}
+	state := make([]int, len(m.ArrayOfBeans))
+	order := make([]int, 0, len(m.ArrayOfBeans))
+	path := make([]string, 0)
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return cycleOf(path, m.ArrayOfBeanNames[i])
+		}
+		state[i] = visiting
+		path = append(path, m.ArrayOfBeanNames[i])
+		for _, dep := range m.dependencies(reflect.ValueOf(m.ArrayOfBeans[i]), nil) {
+			target, ok := index[dep]
+			if !ok || target == i {
+				continue
+			}
+			if err := visit(target); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		order = append(order, i)
+		return nil
+	}
+
+	for i := range m.ArrayOfBeans {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.scopedCycle(); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// scopedCycle error if scoped beans are autowired with each other in a
+// cycle, two goroutines resolving such lazy beans at once would wait for
+// each other; only beans whose factory declares a struct pointer are seen
+func (m *Manager) scopedCycle() error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	path := make([]string, 0)
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return cycleOf(path, name)
+		}
+		state[name] = visiting
+		path = append(path, name)
+		typ := m.scopes[name].typ
+		if typ.Kind() == reflect.Ptr && typ.Elem().Kind() == reflect.Struct {
+			for _, dep := range m.dependencies(reflect.New(typ.Elem()), nil) {
+				if _, ok := m.scopes[dep]; !ok || dep == name {
+					continue
+				}
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for _, name := range m.scopedNames {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cycleOf the cycle error of name, extracted from the current path
+func cycleOf(path []string, name string) error {
+	var cycle = path
+	for k := range path {
+		if path[k] == name {
+			cycle = path[k:]
+			break
+		}
+	}
+	return errors.New("Dependency cycle " + strings.Join(append(cycle, name), " -> "))
+}
@@ -0,0 +1,160 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package winter
+
+import (
+	"strings"
+	"testing"
+)
+
+// recorder a bean recording its post construct in a shared list
+type recorder struct {
+	*Service
+	booted *[]string
+}
+
+// PostConstruct record this bean
+func (p *recorder) PostConstruct(name string) error {
+	*p.booted = append(*p.booted, name)
+	return nil
+}
+
+type leaf struct {
+	*recorder
+}
+
+type middle struct {
+	*recorder
+	Leaf *leaf `@autowired:"leaf"`
+}
+
+type top struct {
+	*recorder
+	Middle *middle `@autowired:"middle"`
+	Leaf   *leaf   `@autowired:"leaf"`
+	// slices and providers do not constrain order
+	All  []IBean      `@autowired:""`
+	Late func() *leaf `@autowired:"leaf"`
+}
+
+type cycleA struct {
+	*Service
+	B *cycleB `@autowired:"b"`
+}
+
+type cycleB struct {
+	*Service
+	C *cycleC `@autowired:"c"`
+}
+
+type cycleC struct {
+	*Service
+	A *cycleA `@autowired:"a"`
+}
+
+// self referencing beans are not a cycle
+type self struct {
+	*Service
+	Self *self `@autowired:"self"`
+}
+
+func TestOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		// registration order
+		beans []string
+	}{
+		{"declared order", []string{"leaf", "middle", "top"}},
+		{"reverse order", []string{"top", "middle", "leaf"}},
+		{"mixed order", []string{"middle", "top", "leaf"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			booted := make([]string, 0)
+			factories := map[string]func() IBean{
+				"leaf":   func() IBean { return &leaf{&recorder{&Service{&Bean{}}, &booted}} },
+				"middle": func() IBean { return &middle{recorder: &recorder{&Service{&Bean{}}, &booted}} },
+				"top":    func() IBean { return &top{recorder: &recorder{&Service{&Bean{}}, &booted}} },
+			}
+			builder := (&Builder{}).New("test")
+			for _, name := range test.beans {
+				builder.Register(name, factories[name])
+			}
+			manager, err := builder.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := manager.Start(nil, ""); err != nil {
+				t.Fatal(err)
+			}
+			defer manager.Shutdown()
+			if strings.Join(booted, ",") != "leaf,middle,top" {
+				t.Errorf("booted %v", booted)
+			}
+		})
+	}
+}
+
+func TestOrderCycle(t *testing.T) {
+	tests := []struct {
+		name  string
+		beans map[string]func() IBean
+		// expected cycle, empty without cycle
+		cycle string
+	}{
+		{"cycle", map[string]func() IBean{
+			"a": func() IBean { return &cycleA{Service: &Service{&Bean{}}} },
+			"b": func() IBean { return &cycleB{Service: &Service{&Bean{}}} },
+			"c": func() IBean { return &cycleC{Service: &Service{&Bean{}}} },
+		}, "Dependency cycle a -> b -> c -> a"},
+		{"self reference", map[string]func() IBean{
+			"self": func() IBean { return &self{Service: &Service{&Bean{}}} },
+		}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builder := (&Builder{}).New("test")
+			for _, name := range []string{"a", "b", "c", "self"} {
+				if factory, ok := test.beans[name]; ok {
+					builder.Register(name, factory)
+				}
+			}
+			manager, err := builder.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = manager.Start(nil, "")
+			if len(test.cycle) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				manager.Shutdown()
+				return
+			}
+			errs, ok := err.(BootErrors)
+			if !ok || len(errs) != 1 || errs[0].Phase != "Order" || errs[0].Err.Error() != test.cycle {
+				t.Errorf("expected %s, got %v", test.cycle, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package winter
+
+import (
+	"strings"
+)
+
+// BootError a single failure of a bean during a lifecycle phase
+type BootError struct {
+	// Bean name
+	Bean string
+	// Phase (Condition, Inject, Order, Listen, PostConstruct, Resources, Validate, PreDestroy)
+	Phase string
+	// Dependency name when an autowired bean is missing
+	Dependency string
+	// Cause
+	Err error
+}
+
+// Error render this error
+func (e *BootError) Error() string {
+	var message = e.Phase + " failed"
+	if len(e.Bean) > 0 {
+		message = message + " for bean '" + e.Bean + "'"
+	}
+	if len(e.Dependency) > 0 {
+		message = message + " on dependency '" + e.Dependency + "'"
+	}
+	if e.Err != nil {
+		message = message + ": " + e.Err.Error()
+	}
+	return message
+}
+
+// BootErrors all failures collected during boot
+type BootErrors []*BootError
+
+// Error render all errors
+func (e BootErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// orNil return nil when no error was collected
+func (e BootErrors) orNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,120 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package winter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// broken a bean autowired with a missing bean
+type broken struct {
+	*Service
+	Leaf *leaf `@autowired:"typo"`
+}
+
+// invalid a bean failing its validation
+type invalid struct {
+	*Service
+}
+
+// Validate fail
+func (p *invalid) Validate(name string) error {
+	return errors.New("invalid")
+}
+
+// panicking a bean panicking in its post construct
+type panicking struct {
+	*Service
+}
+
+// PostConstruct panic
+func (p *panicking) PostConstruct(name string) error {
+	panic("boom")
+}
+
+func TestBootErrors(t *testing.T) {
+	calls := make([]string, 0)
+	failing := func() IBean { return &lifecycle{&Service{&Bean{}}, &calls, true} }
+	tests := []struct {
+		name  string
+		beans map[string]func() IBean
+		// expected errors, bean phase dependency
+		errs [][3]string
+	}{
+		{"missing dependency", map[string]func() IBean{
+			"broken": func() IBean { return &broken{Service: &Service{&Bean{}}} },
+		}, [][3]string{{"broken", "Inject", "typo"}}},
+		{"all failures of a phase", map[string]func() IBean{
+			"a": failing,
+			"b": failing,
+		}, [][3]string{{"a", "PostConstruct", ""}, {"b", "PostConstruct", ""}}},
+		{"panic", map[string]func() IBean{
+			"a": func() IBean { return &panicking{&Service{&Bean{}}} },
+		}, [][3]string{{"a", "PostConstruct", ""}}},
+		{"validation", map[string]func() IBean{
+			"a": func() IBean { return &invalid{&Service{&Bean{}}} },
+		}, [][3]string{{"a", "Validate", ""}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builder := (&Builder{}).New("test")
+			for _, name := range []string{"a", "b", "broken"} {
+				if factory, ok := test.beans[name]; ok {
+					builder.Register(name, factory)
+				}
+			}
+			manager, err := builder.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = manager.Start(nil, "")
+			errs, ok := err.(BootErrors)
+			if !ok {
+				t.Fatalf("got %v", err)
+			}
+			got := make([][3]string, 0)
+			for _, err := range errs {
+				got = append(got, [3]string{err.Bean, err.Phase, err.Dependency})
+			}
+			if !reflect.DeepEqual(got, test.errs) {
+				t.Errorf("got %v", got)
+			}
+		})
+	}
+}
+
+func TestBootErrorsMessage(t *testing.T) {
+	errs := BootErrors{
+		&BootError{Bean: "broken", Phase: "Inject", Dependency: "typo", Err: errors.New("no bean for field Leaf")},
+		&BootError{Phase: "Order", Err: errors.New("Dependency cycle a -> b -> a")},
+	}
+	expected := "Inject failed for bean 'broken' on dependency 'typo': no bean for field Leaf; Order failed: Dependency cycle a -> b -> a"
+	if errs.Error() != expected {
+		t.Errorf("got %s", errs.Error())
+	}
+	if BootErrors(nil).orNil() != nil {
+		t.Error("no error is not nil")
+	}
+}
@@ -0,0 +1,250 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package winter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// BootCompleted published once all beans are validated
+type BootCompleted struct {
+	// Manager name
+	Name string
+}
+
+// EventBus publish/subscribe between beans, events are any typed value
+// delivered to listeners whose argument type they are assignable to;
+// sync listeners are called in subscription order during Publish, each
+// async listener receives its events in publish order
+type EventBus struct {
+	*Service
+	// listeners in subscription order
+	listeners []*listener
+	closed    bool
+	mutex     sync.RWMutex
+}
+
+// IEventBus interface
+type IEventBus interface {
+	IService
+	// Method
+	Subscribe(name string, bean interface{}, method string, async bool) error
+	Listen(name string, bean interface{}) error
+	Publish(event interface{})
+}
+
+// listener a bean method with a single event argument
+type listener struct {
+	bean    string
+	handler string
+	method  reflect.Value
+	typ     reflect.Type
+	async   bool
+	// pending events of async listener
+	pending []interface{}
+	closed  bool
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	done    chan struct{}
+}
+
+// New constructor
+func (b *EventBus) New() IEventBus {
+	bean := EventBus{Service: &Service{Bean: &Bean{}}}
+	bean.listeners = make([]*listener, 0)
+	return &bean
+}
+
+// Init this bean
+func (b *EventBus) Init() error {
+	return nil
+}
+
+// PreDestroy stop accepting events and drain async listeners, it can be
+// called more than once
+func (b *EventBus) PreDestroy(name string) error {
+	b.mutex.Lock()
+	b.closed = true
+	listeners := b.listeners
+	b.mutex.Unlock()
+	for _, l := range listeners {
+		if l.async {
+			l.close()
+		}
+	}
+	return nil
+}
+
+// Listen subscribe all methods named by @listener field tags of this bean,
+// @async:"true" select async delivery
+func (b *EventBus) Listen(name string, bean interface{}) error {
+	types := reflect.TypeOf(bean)
+	if types.Kind() != reflect.Ptr || types.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	types = types.Elem()
+	for i := 0; i < types.NumField(); i++ {
+		field := types.Field(i)
+		method, ok := field.Tag.Lookup("@listener")
+		if !ok {
+			continue
+		}
+		var async = false
+		if value, ok := field.Tag.Lookup("@async"); ok {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return errors.New("invalid @async on field " + field.Name + ": " + err.Error())
+			}
+			async = parsed
+		}
+		if err := b.Subscribe(name, bean, method, async); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe this bean method, it takes the event as single argument and
+// may return an error
+func (b *EventBus) Subscribe(name string, bean interface{}, method string, async bool) error {
+	handler := reflect.ValueOf(bean).MethodByName(method)
+	if !handler.IsValid() {
+		return errors.New("no listener method " + method)
+	}
+	if handler.Type().NumIn() != 1 || handler.Type().NumOut() > 1 {
+		return errors.New("listener method " + method + " must take a single event argument")
+	}
+	l := &listener{bean: name, handler: method, method: handler, typ: handler.Type().In(0), async: async}
+	if async {
+		l.cond = sync.NewCond(&l.mutex)
+		l.done = make(chan struct{})
+		go l.run()
+	}
+	b.mutex.Lock()
+	b.listeners = append(b.listeners, l)
+	b.mutex.Unlock()
+	log.WithFields(log.Fields{
+		"bean":    name,
+		"handler": method,
+		"event":   l.typ.String(),
+		"async":   async,
+	}).Info("Listener")
+	return nil
+}
+
+// Publish this event to all matching listeners
+func (b *EventBus) Publish(event interface{}) {
+	if event == nil {
+		return
+	}
+	var typ = reflect.TypeOf(event)
+	b.mutex.RLock()
+	if b.closed {
+		b.mutex.RUnlock()
+		log.WithFields(log.Fields{
+			"event": typ.String(),
+		}).Warn("Publish after shutdown")
+		return
+	}
+	listeners := b.listeners
+	b.mutex.RUnlock()
+	for _, l := range listeners {
+		if !typ.AssignableTo(l.typ) {
+			continue
+		}
+		if l.async {
+			l.push(event)
+		} else {
+			l.deliver(event)
+		}
+	}
+}
+
+// deliver an event, errors and panics are logged
+func (l *listener) deliver(event interface{}) {
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic in %s: %v", l.handler, r)
+			}
+		}()
+		results := l.method.Call([]reflect.Value{reflect.ValueOf(event)})
+		if len(results) > 0 {
+			if e, ok := results[0].Interface().(error); ok && e != nil {
+				return e
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"bean":    l.bean,
+			"handler": l.handler,
+			"error":   err,
+		}).Error("Listener failed")
+	}
+}
+
+// push an event to the queue of an async listener
+func (l *listener) push(event interface{}) {
+	l.mutex.Lock()
+	if !l.closed {
+		l.pending = append(l.pending, event)
+	}
+	l.mutex.Unlock()
+	l.cond.Signal()
+}
+
+// run deliver queued events in order until closed and drained
+func (l *listener) run() {
+	for {
+		l.mutex.Lock()
+		for len(l.pending) == 0 && !l.closed {
+			l.cond.Wait()
+		}
+		if len(l.pending) == 0 {
+			l.mutex.Unlock()
+			close(l.done)
+			return
+		}
+		event := l.pending[0]
+		l.pending = l.pending[1:]
+		l.mutex.Unlock()
+		l.deliver(event)
+	}
+}
+
+// close an async listener once its queue is drained
+func (l *listener) close() {
+	l.mutex.Lock()
+	l.closed = true
+	l.mutex.Unlock()
+	l.cond.Broadcast()
+	<-l.done
+}
@@ -0,0 +1,121 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package winter
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// audit a bean listening to events in every way
+type audit struct {
+	*Service
+	Booted   interface{} `@listener:"OnBoot"`
+	Anything interface{} `@listener:"OnAny"`
+	Failing  interface{} `@listener:"OnFailure"`
+	Queued   interface{} `@listener:"OnQueued" @async:"true"`
+	// delivered sync events, in delivery order
+	calls []string
+	// delivered async events
+	queued []int
+	mutex  sync.Mutex
+}
+
+// OnBoot record boot events
+func (p *audit) OnBoot(event BootCompleted) {
+	p.calls = append(p.calls, "boot "+event.Name)
+}
+
+// OnAny record any event
+func (p *audit) OnAny(event interface{}) error {
+	p.calls = append(p.calls, "any "+reflect.TypeOf(event).String())
+	return nil
+}
+
+// OnFailure fail or panic on string events
+func (p *audit) OnFailure(event string) error {
+	if event == "panic" {
+		panic(event)
+	}
+	return errors.New(event)
+}
+
+// OnQueued record int events, slowly
+func (p *audit) OnQueued(event int) {
+	time.Sleep(time.Millisecond)
+	p.mutex.Lock()
+	p.queued = append(p.queued, event)
+	p.mutex.Unlock()
+}
+
+func TestEventsSync(t *testing.T) {
+	bus := (&EventBus{}).New()
+	bean := &audit{Service: &Service{&Bean{}}}
+	if err := bus.Listen("audit", bean); err != nil {
+		t.Fatal(err)
+	}
+	bus.Publish(BootCompleted{Name: "test"})
+	// failing listeners do not stop others
+	bus.Publish("failure")
+	bus.Publish("panic")
+	expected := []string{"boot test", "any winter.BootCompleted", "any string", "any string"}
+	if !reflect.DeepEqual(bean.calls, expected) {
+		t.Errorf("got %v", bean.calls)
+	}
+	bus.PreDestroy("events")
+}
+
+func TestEventsAsync(t *testing.T) {
+	bus := (&EventBus{}).New()
+	bean := &audit{Service: &Service{&Bean{}}}
+	if err := bus.Listen("audit", bean); err != nil {
+		t.Fatal(err)
+	}
+	expected := make([]int, 0)
+	for index := 0; index < 20; index++ {
+		bus.Publish(index)
+		expected = append(expected, index)
+	}
+	// drained in publish order
+	if err := bus.PreDestroy("events"); err != nil {
+		t.Fatal(err)
+	}
+	bus.Publish(20)
+	bean.mutex.Lock()
+	defer bean.mutex.Unlock()
+	if !reflect.DeepEqual(bean.queued, expected) {
+		t.Errorf("got %v", bean.queued)
+	}
+}
+
+func TestEventsInvalidListener(t *testing.T) {
+	bus := (&EventBus{}).New()
+	for _, method := range []string{"Missing", "Greet"} {
+		if err := bus.Subscribe("greeter", newGreeter(), method, false); err == nil {
+			t.Errorf("%s subscribed", method)
+		}
+	}
+}
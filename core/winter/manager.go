@@ -23,11 +23,15 @@
 package winter
 
 import (
-	"io"
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"os/signal"
 	"reflect"
 	"strings"
 	"sync"
+	"syscall"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -37,28 +41,6 @@ var (
 	Helper = (&Manager{}).New("manager")
 )
 
-func init() {
-	// Log as JSON instead of the default ASCII formatter.
-	log.SetFormatter(&log.TextFormatter{})
-
-	// Output to stdout instead of the default stderr
-	// Can be any io.Writer, see below for File example
-	log.SetOutput(os.Stdout)
-
-	// Only log the warning severity or above.
-	log.SetLevel(log.DebugLevel)
-
-	// Output to file
-	file, err := os.OpenFile("boot.log", os.O_CREATE|os.O_WRONLY, 0666)
-	if err == nil {
-		var writers io.Writer
-		writers = io.MultiWriter(os.Stderr, file)
-		log.SetOutput(writers)
-	} else {
-		log.Info("Failed to log to file, using default stderr")
-	}
-}
-
 // Manager interface
 type Manager struct {
 	*Service
@@ -67,6 +49,24 @@ type Manager struct {
 	ArrayOfBeanNames []string
 	// Bean registry
 	MapOfBeans map[string]interface{}
+	// Config externalised configuration
+	Config IConfig
+	// Events bus
+	Events IEventBus
+	// Aspects interceptors
+	Aspects IAspects
+	// Bean indexes in dependency order
+	bootOrder []int
+	// Bean indexes whose PostConstruct succeeded, not yet destroyed
+	constructed map[int]bool
+	// Scoped bean definitions
+	scopes      map[string]*scoped
+	scopedNames []string
+	// Lazy bean names in creation order
+	lazies []string
+	mutex  sync.Mutex
+	// Conditional bean definitions, evaluated by Start
+	conditionals []*conditional
 }
 
 // IManager interface
@@ -74,9 +74,20 @@ type IManager interface {
 	IService
 	// Method
 	Register(name string, b IBean) error
-	Boot(PackManager, string) error
+	RegisterScoped(name string, scope Scope, factory interface{}) error
+	RegisterConditional(name string, scope Scope, factory interface{}, conditions ...Condition) error
+	Boot(context.Context, PackManager, string) error
+	Start(PackManager, string) error
+	Shutdown() error
 	GetBean(name string) interface{}
+	GetScopedBean(req IRequest, name string) (interface{}, error)
+	ReleaseRequest(req IRequest) error
 	GetBeanNames() []string
+	Contains(name string) bool
+	Implementations(iface interface{}) []string
+	GetConfig() IConfig
+	GetEventBus() IEventBus
+	GetAspects() IAspects
 	ForEach(func(interface{}))
 }
 
@@ -86,7 +97,14 @@ func (m *Manager) New(name string) IManager {
 	bean.ArrayOfBeans = make([]interface{}, 0)
 	bean.ArrayOfBeanNames = make([]string, 0)
 	bean.MapOfBeans = make(map[string]interface{})
+	bean.scopes = make(map[string]*scoped)
+	bean.Config = (&Config{}).New()
+	bean.Events = (&EventBus{}).New()
+	bean.Aspects = (&Aspects{}).New()
 	bean.Register(name, &bean)
+	bean.Register("config", bean.Config)
+	bean.Register("events", bean.Events)
+	bean.Register("aspects", bean.Aspects)
 	return &bean
 }
 
@@ -113,63 +131,194 @@ func (m *Manager) Register(name string, b IBean) error {
 	return nil
 }
 
-// Boot Init this manager
-func (m *Manager) Boot(box PackManager, notFound string) error {
+// Boot Start this manager, then wait for context cancellation or
+// SIGINT/SIGTERM before destroying all beans
+func (m *Manager) Boot(ctx context.Context, box PackManager, notFound string) error {
+	if err := m.Start(box, notFound); err != nil {
+		return err
+	}
+	// Wait for termination
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	select {
+	case <-ctx.Done():
+		log.WithFields(log.Fields{
+			"cause": ctx.Err(),
+		}).Info("Boot context done")
+	case sig := <-signals:
+		log.WithFields(log.Fields{
+			"signal": sig,
+		}).Info("Boot signal received")
+	}
+	return m.Shutdown()
+}
+
+// Start run all lifecycle phases and return without waiting, any failure
+// stops the boot at the end of its phase and is returned as BootErrors;
+// configuration is loaded beforehand, see Builder.Build and auto.Main
+func (m *Manager) Start(box PackManager, notFound string) error {
+	errs := make(BootErrors, 0)
+	// Conditional beans
+	if errs = m.evaluate(); len(errs) > 0 {
+		return m.abort(errs)
+	}
 	for index := 0; index < len(m.ArrayOfBeans); index++ {
-		m.Inject(m.ArrayOfBeanNames[index], m.ArrayOfBeans[index])
+		err := m.Inject(m.ArrayOfBeanNames[index], m.ArrayOfBeans[index])
+		if err != nil {
+			errs = append(errs, err.(BootErrors)...)
+			continue
+		}
 		log.WithFields(log.Fields{
 			"name": m.ArrayOfBeanNames[index],
 		}).Info("Boot injection sucessfull")
 	}
-	for index := 0; index < len(m.ArrayOfBeans); index++ {
+	if len(errs) > 0 {
+		return m.abort(errs)
+	}
+	// Dependency order
+	order, err := m.order()
+	if err != nil {
+		return m.abort(BootErrors{&BootError{Phase: "Order", Err: err}})
+	}
+	m.bootOrder = order
+	m.constructed = make(map[int]bool)
+	// Listeners are subscribed before any bean may publish
+	for _, index := range m.bootOrder {
+		err := m.Events.Listen(m.ArrayOfBeanNames[index], m.ArrayOfBeans[index])
+		if err != nil {
+			errs = append(errs, &BootError{Bean: m.ArrayOfBeanNames[index], Phase: "Listen", Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return m.abort(errs)
+	}
+	for rank, index := range m.bootOrder {
 		log.WithFields(log.Fields{
-			"index": index,
+			"index": rank,
 			"count": len(m.ArrayOfBeans),
 			"name":  m.ArrayOfBeanNames[index],
 		}).Info("Boot post-construct execute")
-		m.execute(false, m.ArrayOfBeanNames[index], m.ArrayOfBeans[index], "PostConstruct")
+		err := m.execute(false, m.ArrayOfBeanNames[index], m.ArrayOfBeans[index], "PostConstruct")
+		if err != nil {
+			errs = append(errs, &BootError{Bean: m.ArrayOfBeanNames[index], Phase: "PostConstruct", Err: err})
+			continue
+		}
+		m.constructed[index] = true
 		log.WithFields(log.Fields{
-			"index": index,
+			"index": rank,
 			"count": len(m.ArrayOfBeans),
 			"name":  m.ArrayOfBeanNames[index],
 		}).Info("Boot post-construct execute sucessfull")
 	}
-	for index := 0; index < len(m.ArrayOfBeans); index++ {
+	if len(errs) > 0 {
+		return m.abort(errs)
+	}
+	for rank, index := range m.bootOrder {
 		log.WithFields(log.Fields{
-			"index": index,
+			"index": rank,
 			"count": len(m.ArrayOfBeans),
 			"name":  m.ArrayOfBeanNames[index],
-		}).Info("Boot validate execute")
-		m.resources(false, m.ArrayOfBeanNames[index], m.ArrayOfBeans[index], "Resources", box, notFound)
+		}).Info("Boot resources execute")
+		err := m.resources(false, m.ArrayOfBeanNames[index], m.ArrayOfBeans[index], "Resources", box, notFound)
+		if err != nil {
+			errs = append(errs, &BootError{Bean: m.ArrayOfBeanNames[index], Phase: "Resources", Err: err})
+			continue
+		}
 		log.WithFields(log.Fields{
-			"index": index,
+			"index": rank,
 			"count": len(m.ArrayOfBeans),
 			"name":  m.ArrayOfBeanNames[index],
-		}).Info("Boot validate execute sucessfull")
+		}).Info("Boot resources execute sucessfull")
 	}
-	for index := 0; index < len(m.ArrayOfBeans); index++ {
+	if len(errs) > 0 {
+		return m.abort(errs)
+	}
+	for rank, index := range m.bootOrder {
 		log.WithFields(log.Fields{
-			"index": index,
+			"index": rank,
 			"count": len(m.ArrayOfBeans),
 			"name":  m.ArrayOfBeanNames[index],
 		}).Info("Boot validate execute")
-		m.execute(false, m.ArrayOfBeanNames[index], m.ArrayOfBeans[index], "Validate")
+		err := m.execute(false, m.ArrayOfBeanNames[index], m.ArrayOfBeans[index], "Validate")
+		if err != nil {
+			errs = append(errs, &BootError{Bean: m.ArrayOfBeanNames[index], Phase: "Validate", Err: err})
+			continue
+		}
 		log.WithFields(log.Fields{
-			"index": index,
+			"index": rank,
 			"count": len(m.ArrayOfBeans),
 			"name":  m.ArrayOfBeanNames[index],
 		}).Info("Boot validate execute sucessfull")
 	}
-	// Wait infinite
-	var wg sync.WaitGroup
-	wg.Add(1)
-	wg.Wait()
+	if len(errs) > 0 {
+		return m.abort(errs)
+	}
+	m.Events.Publish(BootCompleted{Name: m.GetName()})
 	return nil
 }
 
-// GetBean get bean
+// abort log boot errors and release all beans
+func (m *Manager) abort(errs BootErrors) error {
+	for _, err := range errs {
+		log.WithFields(log.Fields{
+			"name":       err.Bean,
+			"phase":      err.Phase,
+			"dependency": err.Dependency,
+			"error":      err.Err,
+		}).Error("Boot failed")
+	}
+	if m.bootOrder != nil {
+		m.Shutdown()
+	}
+	return errs
+}
+
+// Shutdown drain pending events, destroy lazy beans, then, in reverse boot
+// order, all beans whose PostConstruct succeeded
+func (m *Manager) Shutdown() error {
+	errs := make(BootErrors, 0)
+	// Event bus is closed first, so no listener is called once destroyed
+	err := m.execute(false, m.Events.GetName(), m.Events, "PreDestroy")
+	if err != nil {
+		errs = append(errs, &BootError{Bean: m.Events.GetName(), Phase: "PreDestroy", Err: err})
+	}
+	errs = append(errs, m.destroyLazies()...)
+	for rank := len(m.bootOrder) - 1; rank >= 0; rank-- {
+		var index = m.bootOrder[rank]
+		if !m.constructed[index] {
+			continue
+		}
+		delete(m.constructed, index)
+		if m.ArrayOfBeans[index] == m.Events {
+			continue
+		}
+		log.WithFields(log.Fields{
+			"index": rank,
+			"count": len(m.ArrayOfBeans),
+			"name":  m.ArrayOfBeanNames[index],
+		}).Info("Shutdown pre-destroy execute")
+		err := m.execute(false, m.ArrayOfBeanNames[index], m.ArrayOfBeans[index], "PreDestroy")
+		if err != nil {
+			errs = append(errs, &BootError{Bean: m.ArrayOfBeanNames[index], Phase: "PreDestroy", Err: err})
+		}
+	}
+	return errs.orNil()
+}
+
+// GetBean get bean, lazy and prototype beans are created as needed
 func (m *Manager) GetBean(name string) interface{} {
-	return m.MapOfBeans[name]
+	if _, ok := m.scopes[name]; !ok {
+		return m.MapOfBeans[name]
+	}
+	bean, err := m.resolve(name, &injection{demand: true})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"name":  name,
+			"error": err,
+		}).Error("GetBean")
+	}
+	return bean
 }
 
 // GetBeanNames get bean
@@ -177,10 +326,29 @@ func (m *Manager) GetBeanNames() []string {
 	return m.ArrayOfBeanNames
 }
 
-// Inject this API
+// GetConfig get configuration
+func (m *Manager) GetConfig() IConfig {
+	return m.Config
+}
+
+// GetEventBus get event bus
+func (m *Manager) GetEventBus() IEventBus {
+	return m.Events
+}
+
+// GetAspects get interceptors registry
+func (m *Manager) GetAspects() IAspects {
+	return m.Aspects
+}
+
+// Inject this API, return BootErrors for each unresolved dependency
+// or unconvertible @value property
 func (m *Manager) Inject(name string, intf interface{}) error {
-	m.autowire(false, 0, name, intf, reflect.ValueOf(intf))
-	return nil
+	errs := m.autowire(false, 0, name, intf, reflect.ValueOf(intf), &injection{}, nil)
+	if err := m.Config.Bind(name, intf); err != nil {
+		errs = append(errs, err.(BootErrors)...)
+	}
+	return errs.orNil()
 }
 
 // dumpFields dump all fields
@@ -189,7 +357,7 @@ func (m *Manager) isPrivate(val reflect.StructField) bool {
 }
 
 // dumpFields dump all fields
-func (m *Manager) autowire(debug bool, level int, name string, intf interface{}, val reflect.Value) {
+func (m *Manager) autowire(debug bool, level int, name string, intf interface{}, val reflect.Value, in *injection, errs BootErrors) BootErrors {
 	if debug {
 		log.WithFields(log.Fields{
 			"level": level,
@@ -207,26 +375,30 @@ func (m *Manager) autowire(debug bool, level int, name string, intf interface{},
 	// Interface case and Pointer case
 	if kind == reflect.Interface || kind == reflect.Ptr {
 		if !val.IsNil() {
-			m.autowire(debug, level+1, name, intf, val.Elem())
+			return m.autowire(debug, level+1, name, intf, val.Elem(), in, errs)
 		}
-		return
+		return errs
 	}
 
 	// Function case
 	if kind == reflect.Func {
-		return
+		return errs
 	}
 	// Ignore primitive types
 	if kind == reflect.Slice {
-		return
+		return errs
 	}
 	// Ignore primitive types
 	if kind == reflect.String {
-		return
+		return errs
 	}
 	// Ignore primitive types
 	if kind == reflect.Map {
-		return
+		return errs
+	}
+	// Ignore other primitive types
+	if kind != reflect.Struct {
+		return errs
 	}
 
 	if debug {
@@ -255,19 +427,30 @@ func (m *Manager) autowire(debug bool, level int, name string, intf interface{},
 	for i := 0; i < val.NumField(); i++ {
 		valueField := val.Field(i)
 		typeField := val.Type().Field(i)
-		tag := typeField.Tag
 		if !m.isPrivate(typeField) {
-			if len(tag.Get("@autowired")) > 0 {
+			if beanName, ok := m.autowired(typeField); ok {
 				if valueField.IsNil() {
-					var beanName = tag.Get("@autowired")
-					// myBean contain the target bean to inject
-					var myBean = m.MapOfBeans[beanName]
+					// candidates contain the target beans to inject
+					candidates, err := m.candidates(beanName, typeField)
+					if err != nil && m.optional(typeField) {
+						log.WithFields(log.Fields{
+							"name":  typeField.Name,
+							"error": err,
+						}).Debug("Optional")
+						continue
+					}
+					if err != nil {
+						errs = append(errs, &BootError{Bean: name, Phase: "Inject", Dependency: m.dependency(beanName, typeField), Err: err})
+						continue
+					}
 					log.WithFields(log.Fields{
-						"bean": myBean,
+						"bean": candidates,
 						"name": typeField.Name,
 					}).Debug("Set")
-					//setter.Call(arguments)
-					valueField.Set(reflect.ValueOf(myBean))
+					err = m.set(valueField, typeField, candidates, in)
+					if err != nil {
+						errs = append(errs, &BootError{Bean: name, Phase: "Inject", Dependency: m.dependency(beanName, typeField), Err: err})
+					}
 				}
 			}
 		}
@@ -276,52 +459,164 @@ func (m *Manager) autowire(debug bool, level int, name string, intf interface{},
 	for i := 0; i < val.NumField(); i++ {
 		valueField := val.Field(i)
 		typeField := val.Type().Field(i)
-		tag := typeField.Tag
 		if !m.isPrivate(typeField) {
 			// autowired fields are excluded for recursive init
-			if !(len(tag.Get("@autowired")) > 0) {
-				m.autowire(debug, level+1, name, valueField.Interface(), valueField)
+			if _, ok := m.autowired(typeField); !ok {
+				errs = m.autowire(debug, level+1, name, valueField.Interface(), valueField, in, errs)
 			}
 		}
 	}
+	return errs
 }
 
-// dumpFields dump all fields
-func (m *Manager) resources(debug bool, beanName string, intf interface{}, handler string, resources PackManager, notFound string) {
-	val := reflect.ValueOf(intf)
-	for i := 0; i < val.NumMethod(); i++ {
-		typeMethod := val.Type().Method(i)
-		if typeMethod.Name == handler {
-			var setter = reflect.ValueOf(intf).MethodByName(handler)
-			arr := [3]reflect.Value{reflect.ValueOf(beanName), reflect.ValueOf(resources), reflect.ValueOf(notFound)}
-			var arguments = arr[:3]
-			if debug {
-				log.WithFields(log.Fields{
-					"handler": handler,
-					"name":    beanName,
-				}).Debug("Execute")
-			}
-			setter.Call(arguments)
+// optional check if an autowired field may stay nil when no bean matches
+func (m *Manager) optional(field reflect.StructField) bool {
+	return field.Tag.Get("@optional") == "true"
+}
+
+// autowired return the bean name of an autowired field, an empty name
+// means injection by type
+func (m *Manager) autowired(field reflect.StructField) (string, bool) {
+	return field.Tag.Lookup("@autowired")
+}
+
+// dependency describe the dependency of an autowired field
+func (m *Manager) dependency(beanName string, field reflect.StructField) string {
+	if len(beanName) > 0 {
+		return beanName
+	}
+	if qualifier := field.Tag.Get("@qualifier"); len(qualifier) > 0 {
+		return qualifier
+	}
+	return field.Type.String()
+}
+
+// set an autowired field, slices receive all candidates, provider funcs
+// resolve their candidate on each call; beans are wrapped in the proxy
+// declared for the field type if any
+func (m *Manager) set(value reflect.Value, field reflect.StructField, candidates []string, in *injection) error {
+	switch field.Type.Kind() {
+	case reflect.Slice:
+		slice := reflect.MakeSlice(field.Type, 0, len(candidates))
+		for _, candidate := range candidates {
+			bean := m.Aspects.Proxy(candidate, field.Type.Elem(), m.MapOfBeans[candidate])
+			slice = reflect.Append(slice, reflect.ValueOf(bean))
+		}
+		value.Set(slice)
+	case reflect.Func:
+		value.Set(m.provider(field.Type, candidates[0]))
+	default:
+		bean, err := m.resolve(candidates[0], in)
+		if err != nil {
+			return err
+		}
+		value.Set(reflect.ValueOf(m.Aspects.Proxy(candidates[0], field.Type, bean)))
+	}
+	return nil
+}
+
+// target return the bean type of an autowired field, slices expect their
+// element type and provider funcs their result type
+func (m *Manager) target(field reflect.StructField) (reflect.Type, error) {
+	var typ = field.Type
+	switch typ.Kind() {
+	case reflect.Slice:
+		return typ.Elem(), nil
+	case reflect.Func:
+		if typ.NumOut() != 1 || typ.NumIn() > 1 || (typ.NumIn() == 1 && typ.In(0) != requestType) {
+			return nil, errors.New("provider field " + field.Name + " must be a func() or func(winter.IRequest) with one result")
 		}
+		return typ.Out(0), nil
 	}
+	return typ, nil
 }
 
-// dumpFields dump all fields
-func (m *Manager) execute(debug bool, beanName string, intf interface{}, handler string) {
-	val := reflect.ValueOf(intf)
-	for i := 0; i < val.NumMethod(); i++ {
-		typeMethod := val.Type().Method(i)
-		if typeMethod.Name == handler {
-			var setter = reflect.ValueOf(intf).MethodByName(handler)
-			arr := [1]reflect.Value{reflect.ValueOf(beanName)}
-			var arguments = arr[:1]
-			if debug {
-				log.WithFields(log.Fields{
-					"handler": handler,
-					"name":    beanName,
-				}).Debug("Execute")
-			}
-			setter.Call(arguments)
+// candidates resolve bean names for an autowired field, by name, by
+// qualifier or by type; slice fields receive every implementing singleton
+func (m *Manager) candidates(beanName string, field reflect.StructField) ([]string, error) {
+	target, err := m.target(field)
+	if err != nil {
+		return nil, err
+	}
+	var slice = field.Type.Kind() == reflect.Slice
+	// By name or qualifier
+	if len(beanName) == 0 {
+		beanName = field.Tag.Get("@qualifier")
+	}
+	if len(beanName) > 0 {
+		var typ reflect.Type
+		if definition, ok := m.scopes[beanName]; ok {
+			typ = definition.typ
+		} else if bean, ok := m.MapOfBeans[beanName]; ok && bean != nil {
+			typ = reflect.TypeOf(bean)
+		} else {
+			return nil, errors.New("no bean for field " + field.Name)
 		}
+		if !typ.AssignableTo(target) {
+			return nil, errors.New(typ.String() + " is not assignable to field " + field.Name)
+		}
+		return []string{beanName}, nil
+	}
+	// By type
+	candidates := make([]string, 0)
+	for index, bean := range m.ArrayOfBeans {
+		if bean != nil && reflect.TypeOf(bean).AssignableTo(target) {
+			candidates = append(candidates, m.ArrayOfBeanNames[index])
+		}
+	}
+	if slice {
+		return candidates, nil
+	}
+	for _, name := range m.scopedNames {
+		if m.scopes[name].typ.AssignableTo(target) {
+			candidates = append(candidates, name)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, errors.New("no bean implements " + target.String() + " for field " + field.Name)
+	}
+	if len(candidates) > 1 {
+		return nil, errors.New("beans " + strings.Join(candidates, ", ") + " all implement " + target.String() + " for field " + field.Name + ", use @qualifier")
+	}
+	return candidates, nil
+}
+
+// resources call the resources handler of this bean if any, nothing is
+// called without resources
+func (m *Manager) resources(debug bool, beanName string, intf interface{}, handler string, resources PackManager, notFound string) error {
+	if resources == nil {
+		return nil
 	}
+	return m.call(debug, beanName, intf, handler, reflect.ValueOf(beanName), reflect.ValueOf(resources), reflect.ValueOf(notFound))
+}
+
+// execute call a lifecycle handler of this bean if any
+func (m *Manager) execute(debug bool, beanName string, intf interface{}, handler string) error {
+	return m.call(debug, beanName, intf, handler, reflect.ValueOf(beanName))
+}
+
+// call a handler by reflection, returned error and panic are both reported
+func (m *Manager) call(debug bool, beanName string, intf interface{}, handler string, arguments ...reflect.Value) (err error) {
+	var setter = reflect.ValueOf(intf).MethodByName(handler)
+	if !setter.IsValid() {
+		return nil
+	}
+	if debug {
+		log.WithFields(log.Fields{
+			"handler": handler,
+			"name":    beanName,
+		}).Debug("Execute")
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic in %s: %v", handler, r)
+		}
+	}()
+	results := setter.Call(arguments)
+	if len(results) > 0 {
+		if e, ok := results[len(results)-1].Interface().(error); ok && e != nil {
+			return e
+		}
+	}
+	return nil
 }
@@ -0,0 +1,274 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package winter
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Scope of a bean
+type Scope int
+
+const (
+	// Singleton one instance per manager, created at registration
+	Singleton Scope = iota
+	// Lazy one instance per manager, created on first use
+	Lazy
+	// Prototype a new instance on each use
+	Prototype
+	// Request one instance per http request
+	Request
+)
+
+// String render this scope
+func (s Scope) String() string {
+	return [...]string{"singleton", "lazy", "prototype", "request"}[s]
+}
+
+const (
+	// requestBeanKey prefix of request scoped beans in request attributes
+	requestBeanKey = "winter.bean."
+	// requestNamesKey request scoped bean names in creation order
+	requestNamesKey = "winter.request"
+)
+
+var (
+	ibeanType   = reflect.TypeOf((*IBean)(nil)).Elem()
+	requestType = reflect.TypeOf((*IRequest)(nil)).Elem()
+)
+
+// IRequest request attributes, gin context implements it
+type IRequest interface {
+	Set(key string, value interface{})
+	Get(key string) (interface{}, bool)
+}
+
+// scoped bean definition
+type scoped struct {
+	scope   Scope
+	factory reflect.Value
+	// declared result type of factory
+	typ reflect.Type
+	// lazy instance
+	instance interface{}
+	mutex    sync.Mutex
+}
+
+// injection state of a bean being autowired
+type injection struct {
+	// request of request scoped dependencies
+	req IRequest
+	// scoped beans can be injected directly in beans created on demand
+	demand bool
+	// beans being created, for cycle detection
+	path []string
+}
+
+// RegisterScoped register a bean factory, factory is a func without
+// argument returning the bean; its declared result type is used when
+// autowiring by type. Scoped beans are created on demand, singletons
+// reach them with a provider field func() T or func(winter.IRequest) T
+func (m *Manager) RegisterScoped(name string, scope Scope, factory interface{}) error {
+	value := reflect.ValueOf(factory)
+	if value.Kind() != reflect.Func || value.Type().NumIn() != 0 || value.Type().NumOut() != 1 || !value.Type().Out(0).Implements(ibeanType) {
+		return errors.New("factory of bean '" + name + "' must be a func() returning a winter.IBean")
+	}
+	if scope == Singleton {
+		bean, ok := value.Call(nil)[0].Interface().(IBean)
+		if !ok {
+			return errors.New("factory of bean '" + name + "' returned nil")
+		}
+		return m.Register(name, bean)
+	}
+	m.scopes[name] = &scoped{scope: scope, factory: value, typ: value.Type().Out(0)}
+	m.scopedNames = append(m.scopedNames, name)
+	return nil
+}
+
+// GetScopedBean get a bean of any scope, request scoped beans are bound
+// to this request
+func (m *Manager) GetScopedBean(req IRequest, name string) (interface{}, error) {
+	if _, ok := m.scopes[name]; !ok {
+		if bean, ok := m.MapOfBeans[name]; ok {
+			return bean, nil
+		}
+		return nil, errors.New("no bean '" + name + "'")
+	}
+	return m.resolve(name, &injection{req: req, demand: true})
+}
+
+// ReleaseRequest destroy all request scoped beans of this request
+func (m *Manager) ReleaseRequest(req IRequest) error {
+	value, _ := req.Get(requestNamesKey)
+	names, _ := value.([]string)
+	errs := make(BootErrors, 0)
+	for rank := len(names) - 1; rank >= 0; rank-- {
+		bean, _ := req.Get(requestBeanKey + names[rank])
+		err := m.execute(false, names[rank], bean, "PreDestroy")
+		if err != nil {
+			errs = append(errs, &BootError{Bean: names[rank], Phase: "PreDestroy", Err: err})
+		}
+	}
+	req.Set(requestNamesKey, []string{})
+	return errs.orNil()
+}
+
+// resolve a bean by name, scoped beans are created as needed
+func (m *Manager) resolve(name string, in *injection) (interface{}, error) {
+	definition, ok := m.scopes[name]
+	if !ok {
+		return m.MapOfBeans[name], nil
+	}
+	if !in.demand {
+		return nil, errors.New("scoped bean '" + name + "' must be injected with a provider func")
+	}
+	// before any lock, a lazy bean in its own path would wait for itself
+	if err := cycle(name, in); err != nil {
+		return nil, err
+	}
+	switch definition.scope {
+	case Lazy:
+		definition.mutex.Lock()
+		defer definition.mutex.Unlock()
+		if definition.instance == nil {
+			bean, err := m.create(name, definition, in)
+			if err != nil {
+				return nil, err
+			}
+			definition.instance = bean
+			m.mutex.Lock()
+			m.lazies = append(m.lazies, name)
+			m.mutex.Unlock()
+		}
+		return definition.instance, nil
+	case Request:
+		if in.req == nil {
+			return nil, errors.New("request scoped bean '" + name + "' needs a request")
+		}
+		if bean, ok := in.req.Get(requestBeanKey + name); ok {
+			return bean, nil
+		}
+		bean, err := m.create(name, definition, in)
+		if err != nil {
+			return nil, err
+		}
+		value, _ := in.req.Get(requestNamesKey)
+		names, _ := value.([]string)
+		in.req.Set(requestBeanKey+name, bean)
+		in.req.Set(requestNamesKey, append(names, name))
+		return bean, nil
+	default:
+		return m.create(name, definition, in)
+	}
+}
+
+// create a scoped bean, then inject, post-construct and validate it
+func (m *Manager) create(name string, definition *scoped, in *injection) (interface{}, error) {
+	if err := cycle(name, in); err != nil {
+		return nil, err
+	}
+	bean, ok := definition.factory.Call(nil)[0].Interface().(IBean)
+	if !ok {
+		return nil, errors.New("factory of bean '" + name + "' returned nil")
+	}
+	bean.SetName(name)
+	bean.Init()
+	inner := &injection{req: in.req, demand: true, path: append(append([]string{}, in.path...), name)}
+	errs := m.autowire(false, 0, name, bean, reflect.ValueOf(bean), inner, nil)
+	if err := m.Config.Bind(name, bean); err != nil {
+		errs = append(errs, err.(BootErrors)...)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	for _, handler := range []string{"PostConstruct", "Validate"} {
+		if err := m.execute(false, name, bean, handler); err != nil {
+			return nil, BootErrors{&BootError{Bean: name, Phase: handler, Err: err}}
+		}
+	}
+	log.WithFields(log.Fields{
+		"name":  name,
+		"scope": definition.scope,
+	}).Debug("Scoped bean created")
+	return bean, nil
+}
+
+// cycle error if this bean is already being created in this injection
+func cycle(name string, in *injection) error {
+	for _, creating := range in.path {
+		if creating == name {
+			return errors.New("Dependency cycle " + strings.Join(append(in.path, name), " -> "))
+		}
+	}
+	return nil
+}
+
+// provider build a func resolving this bean on each call, its optional
+// argument is the request of request scoped beans
+func (m *Manager) provider(typ reflect.Type, name string) reflect.Value {
+	return reflect.MakeFunc(typ, func(args []reflect.Value) []reflect.Value {
+		in := &injection{demand: true}
+		if len(args) > 0 && !args[0].IsNil() {
+			in.req = args[0].Interface().(IRequest)
+		}
+		result := reflect.New(typ.Out(0)).Elem()
+		bean, err := m.resolve(name, in)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"name":  name,
+				"error": err,
+			}).Error("Provider")
+			return []reflect.Value{result}
+		}
+		if bean != nil {
+			result.Set(reflect.ValueOf(m.Aspects.Proxy(name, typ.Out(0), bean)))
+		}
+		return []reflect.Value{result}
+	})
+}
+
+// destroyLazies destroy lazy beans in reverse creation order
+func (m *Manager) destroyLazies() BootErrors {
+	m.mutex.Lock()
+	lazies := m.lazies
+	m.lazies = nil
+	m.mutex.Unlock()
+	errs := make(BootErrors, 0)
+	for rank := len(lazies) - 1; rank >= 0; rank-- {
+		var name = lazies[rank]
+		definition := m.scopes[name]
+		err := m.execute(false, name, definition.instance, "PreDestroy")
+		if err != nil {
+			errs = append(errs, &BootError{Bean: name, Phase: "PreDestroy", Err: err})
+		}
+		definition.mutex.Lock()
+		definition.instance = nil
+		definition.mutex.Unlock()
+	}
+	return errs
+}
@@ -0,0 +1,207 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package winter
+
+import (
+	"sync"
+	"testing"
+)
+
+// counted a scoped bean counting its instances
+type counted struct {
+	*Service
+}
+
+// request attributes of a fake http request
+type request map[string]interface{}
+
+func (r request) Set(key string, value interface{}) { r[key] = value }
+
+func (r request) Get(key string) (interface{}, bool) {
+	value, ok := r[key]
+	return value, ok
+}
+
+// lazyA and lazyB lazy beans autowired with each other
+type lazyA struct {
+	*Service
+	B *lazyB `@autowired:"b"`
+}
+
+type lazyB struct {
+	*Service
+	A *lazyA `@autowired:"a"`
+}
+
+// lazyChain a lazy bean autowired with another one
+type lazyChain struct {
+	*Service
+	Counted *counted `@autowired:"counted"`
+}
+
+// scopedManager a started manager with a counted bean of this scope
+func scopedManager(t *testing.T, scope Scope, created *int) IManager {
+	manager, err := (&Builder{}).New("test").
+		RegisterScoped("counted", scope, func() *counted {
+			*created++
+			return &counted{Service: &Service{Bean: &Bean{}}}
+		}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Start(nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	return manager
+}
+
+func TestScopes(t *testing.T) {
+	first, second := request{}, request{}
+	tests := []struct {
+		scope Scope
+		// requests of the three lookups
+		requests []IRequest
+		// created instances once looked up
+		created int
+		// same instance on the first two lookups
+		same bool
+	}{
+		{Lazy, []IRequest{nil, nil, nil}, 1, true},
+		{Prototype, []IRequest{nil, nil, nil}, 3, false},
+		{Request, []IRequest{first, first, second}, 2, true},
+	}
+	for _, test := range tests {
+		t.Run(test.scope.String(), func(t *testing.T) {
+			created := 0
+			manager := scopedManager(t, test.scope, &created)
+			defer manager.Shutdown()
+			if created != 0 {
+				t.Fatalf("%v bean created at boot", test.scope)
+			}
+			beans := make([]interface{}, 0)
+			for _, req := range test.requests {
+				bean, err := manager.GetScopedBean(req, "counted")
+				if err != nil {
+					t.Fatal(err)
+				}
+				beans = append(beans, bean)
+			}
+			if created != test.created {
+				t.Errorf("created %d instances, expected %d", created, test.created)
+			}
+			if (beans[0] == beans[1]) != test.same {
+				t.Errorf("same instance is %v, expected %v", beans[0] == beans[1], test.same)
+			}
+		})
+	}
+}
+
+func TestRequestScopeNeedsRequest(t *testing.T) {
+	created := 0
+	manager := scopedManager(t, Request, &created)
+	defer manager.Shutdown()
+	if _, err := manager.GetScopedBean(nil, "counted"); err == nil {
+		t.Error("request scoped bean resolved without request")
+	}
+}
+
+func TestLazyCycle(t *testing.T) {
+	manager, err := (&Builder{}).New("test").
+		RegisterScoped("a", Lazy, func() *lazyA { return &lazyA{Service: &Service{Bean: &Bean{}}} }).
+		RegisterScoped("b", Lazy, func() *lazyB { return &lazyB{Service: &Service{Bean: &Bean{}}} }).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rejected at boot, a and b resolved from two goroutines would wait for
+	// each other
+	err = manager.Start(nil, "")
+	errs, ok := err.(BootErrors)
+	if !ok || len(errs) != 1 || errs[0].Phase != "Order" || errs[0].Err.Error() != "Dependency cycle a -> b -> a" {
+		t.Errorf("expected a dependency cycle, got %v", err)
+	}
+}
+
+func TestLazyChain(t *testing.T) {
+	manager, err := (&Builder{}).New("test").
+		RegisterScoped("a", Lazy, func() *lazyChain { return &lazyChain{Service: &Service{Bean: &Bean{}}} }).
+		RegisterScoped("counted", Lazy, func() *counted { return &counted{Service: &Service{Bean: &Bean{}}} }).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Start(nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Shutdown()
+	var group sync.WaitGroup
+	beans := make([]interface{}, 2)
+	for index, name := range []string{"a", "counted"} {
+		group.Add(1)
+		go func(index int, name string) {
+			defer group.Done()
+			beans[index], _ = manager.GetScopedBean(nil, name)
+		}(index, name)
+	}
+	group.Wait()
+	if chain, ok := beans[0].(*lazyChain); !ok || chain.Counted != beans[1] {
+		t.Errorf("got %v", beans)
+	}
+}
+
+func TestLazyConcurrent(t *testing.T) {
+	builder := (&Builder{}).New("test")
+	names := []string{"l0", "l1", "l2", "l3"}
+	for _, name := range names {
+		builder.RegisterScoped(name, Lazy, func() *counted { return &counted{Service: &Service{Bean: &Bean{}}} })
+	}
+	manager, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Start(nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	var group sync.WaitGroup
+	beans := make([]interface{}, 4*len(names))
+	for index := range beans {
+		group.Add(1)
+		go func(index int) {
+			defer group.Done()
+			beans[index], _ = manager.GetScopedBean(nil, names[index%len(names)])
+		}(index)
+	}
+	group.Wait()
+	for index := range beans {
+		if beans[index] == nil || beans[index] != beans[index%len(names)] {
+			t.Fatalf("lazy bean %s created twice", names[index%len(names)])
+		}
+	}
+	if lazies := manager.(*Manager).lazies; len(lazies) != len(names) {
+		t.Errorf("%d lazy beans recorded, expected %d", len(lazies), len(names))
+	}
+	if err := manager.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+}
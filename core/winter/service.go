@@ -47,3 +47,8 @@ func (p *Service) PostConstruct(name string) error {
 func (p *Service) Validate(name string) error {
 	return p.Bean.Validate(name)
 }
+
+// PreDestroy release this bean
+func (p *Service) PreDestroy(name string) error {
+	return p.Bean.PreDestroy(name)
+}
@@ -0,0 +1,152 @@
+// Package apis for common interfaces
+// MIT License
+//
+// Copyright (c) 2017 yroffin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package winter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// lifecycle a bean recording its post construct and pre destroy in a
+// shared list, its post construct fails on demand
+type lifecycle struct {
+	*Service
+	calls *[]string
+	fail  bool
+}
+
+// PostConstruct record this bean
+func (p *lifecycle) PostConstruct(name string) error {
+	*p.calls = append(*p.calls, "construct "+name)
+	if p.fail {
+		return errors.New("failure")
+	}
+	return nil
+}
+
+// PreDestroy record this bean
+func (p *lifecycle) PreDestroy(name string) error {
+	*p.calls = append(*p.calls, "destroy "+name)
+	return nil
+}
+
+// dependent a lifecycle bean autowired with first
+type dependent struct {
+	*lifecycle
+	First *lifecycle `@autowired:"first"`
+}
+
+// closingBus an event bus counting its pre destroy
+type closingBus struct {
+	IEventBus
+	closed int
+}
+
+// PreDestroy count and close
+func (b *closingBus) PreDestroy(name string) error {
+	b.closed++
+	return b.IEventBus.(*EventBus).PreDestroy(name)
+}
+
+// withClosingBus replace the event bus of this manager
+func withClosingBus(manager IManager) *closingBus {
+	m := manager.(*Manager)
+	bus := &closingBus{IEventBus: m.Events}
+	for index, bean := range m.ArrayOfBeans {
+		if bean == m.Events {
+			m.ArrayOfBeans[index] = bus
+		}
+	}
+	m.MapOfBeans[bus.GetName()] = bus
+	m.Events = bus
+	return bus
+}
+
+func TestShutdownConstructed(t *testing.T) {
+	tests := []struct {
+		name string
+		// beans whose post construct fails
+		failing map[string]bool
+		calls   string
+	}{
+		{"all constructed", nil, "construct first,construct second,construct third,destroy third,destroy second,destroy first"},
+		{"failed construct", map[string]bool{"second": true}, "construct first,construct second,construct third,destroy third,destroy first"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := make([]string, 0)
+			builder := (&Builder{}).New("test")
+			for _, name := range []string{"first", "second", "third"} {
+				fail := test.failing[name]
+				builder.Register(name, func() IBean { return &lifecycle{&Service{&Bean{}}, &calls, fail} })
+			}
+			manager, err := builder.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			bus := withClosingBus(manager)
+			if err := manager.Start(nil, ""); err != nil {
+				if len(test.failing) == 0 {
+					t.Fatal(err)
+				}
+			} else {
+				manager.Shutdown()
+			}
+			// a second shutdown destroys nothing more
+			manager.Shutdown()
+			if strings.Join(calls, ",") != test.calls {
+				t.Errorf("got %v", calls)
+			}
+			if bus.closed != 2 {
+				t.Errorf("event bus closed %d times over two shutdowns", bus.closed)
+			}
+		})
+	}
+}
+
+func TestBootShutdown(t *testing.T) {
+	calls := make([]string, 0)
+	// registered before the bean it depends on
+	manager, err := (&Builder{}).New("test").
+		Register("dependent", func() IBean { return &dependent{lifecycle: &lifecycle{&Service{&Bean{}}, &calls, false}} }).
+		Register("first", func() IBean { return &lifecycle{&Service{&Bean{}}, &calls, false} }).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- manager.Boot(ctx, nil, "")
+	}()
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	// destroyed in reverse boot order
+	if strings.Join(calls, ",") != "construct first,construct dependent,destroy dependent,destroy first" {
+		t.Errorf("got %v", calls)
+	}
+}
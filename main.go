@@ -22,7 +22,10 @@
 package main
 
 import (
+	"context"
+
 	"github.com/gobuffalo/packr"
+	log "github.com/sirupsen/logrus"
 	auto "github.com/yroffin/go-boot-sqllite/core/auto"
 	"github.com/yroffin/go-boot-sqllite/core/winter"
 )
@@ -34,6 +37,11 @@ func PackInstance() winter.PackManager {
 }
 
 func main() {
-	// Boot
-	winter.Helper.Boot(PackInstance())
+	// Boot, return on SIGINT/SIGTERM once all beans are destroyed
+	err := auto.Main(context.Background(), PackInstance(), "index.html")
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err,
+		}).Fatal("Boot")
+	}
 }